@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// auditEntry is a single line in the audit log. The log is kept next to the
+// config file unless 'audit_log' points somewhere else.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Action  string    `json:"action"`
+	Tokens  []string  `json:"tokens,omitempty"`
+	Secrets []string  `json:"secrets,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func writeAudit(entry auditEntry, err error) error {
+	entry.Time = time.Now().UTC()
+	entry.Host, _ = os.Hostname()
+	if u, uerr := user.Current(); uerr == nil {
+		entry.User = u.Username
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	path := configValue("audit_log")
+	if path == "" {
+		path = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), "startup_nights_audit.log")
+	}
+
+	f, ferr := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if ferr != nil {
+		return errors.Wrap(ferr, "open audit log")
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return errors.Wrap(err, "write audit log")
+	}
+
+	return nil
+}
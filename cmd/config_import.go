@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a config shared with 'config share'",
+		Long: `Decrypts a config shared with 'config share' and updates the config. The
+file has to be shared by one of the 'recipients' in the own config.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !configIsSet("private_key") {
+				cobra.CheckErr(errors.New("no private key configured, run: snctl config keygen"))
+			}
+
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				cobra.CheckErr(errors.Wrap(err, "read shared config"))
+			}
+
+			shared := &functions.SharedConfig{}
+			if err := yaml.Unmarshal(data, shared); err != nil {
+				cobra.CheckErr(errors.Wrap(err, "decode shared config"))
+			}
+
+			// only the keys of the own config are trusted, the file itself
+			// can be written by anyone with access to the repository
+			own, err := functions.PublicKey(configValue("private_key"))
+			if err != nil {
+				cobra.CheckErr(err)
+			}
+
+			trusted := []string{own}
+			for _, key := range viper.GetStringMapString(configKey("recipients")) {
+				trusted = append(trusted, key)
+			}
+
+			values, err := shared.Open(configValue("private_key"), trusted)
+			if err != nil {
+				cobra.CheckErr(errors.Wrap(err, "decrypt shared config"))
+			}
+
+			// 'config share' resolves references before sealing them, a
+			// reference in the file would be resolved (or run) on every read
+			for _, key := range sharedKeys {
+				if isReference(values[key]) {
+					cobra.CheckErr(errors.Errorf("%s is a reference, refusing to import it", key))
+				}
+			}
+
+			for _, key := range sharedKeys {
+				value, ok := values[key]
+				if !ok {
+					continue
+				}
+
+				if err := secretStore().Set(key, value); err != nil {
+					cobra.CheckErr(err)
+				}
+
+				fmt.Printf("imported %s\n", key)
+			}
+		},
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configImportCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// wizardField is a value asked for by 'config init'. Fields with file set
+// read the value from the file the user enters.
+type wizardField struct {
+	key    string
+	label  string
+	secret bool
+	file   bool
+}
+
+var wizardFields = []wizardField{
+	{key: "spaces_bucket", label: "spaces bucket"},
+	{key: "spaces_region", label: "spaces region"},
+	{key: "spaces_key", label: "spaces access key"},
+	{key: "spaces_secret", label: "spaces secret", secret: true},
+	{key: "credentials", label: "path to the oauth client credentials (json)", file: true},
+	{key: "github_token", label: "github token", secret: true},
+}
+
+var (
+	configInitCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create or update the config file interactively",
+		Long: `Asks for the spaces credentials, the oauth client credentials and the github
+token and writes them to the config file (--config, default
+$HOME/.config/startup_nights.yaml). Existing values are kept if the input is
+left empty. With --profile the values are written to that profile.`,
+		Annotations: map[string]string{configAnnotation: "optional"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if viper.ConfigFileUsed() == "" || !fileExists(viper.ConfigFileUsed()) {
+				path, err := createConfigFile()
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				fmt.Printf("=> creating %s\n", path)
+			}
+
+			in := bufio.NewReader(os.Stdin)
+			for _, field := range wizardFields {
+				value, err := promptValue(in, field)
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				if value == "" {
+					continue
+				}
+
+				if err := secretStore().Set(field.key, value); err != nil {
+					cobra.CheckErr(err)
+				}
+			}
+
+			fmt.Printf("=> wrote %s, tokens can be added with: snctl token update\n", viper.ConfigFileUsed())
+		},
+	}
+)
+
+// createConfigFile creates an empty config file, readable only by the user.
+func createConfigFile() (string, error) {
+	path := cfgFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "find home directory")
+		}
+
+		path = filepath.Join(home, ".config", "startup_nights.yaml")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", errors.Wrap(err, "create config directory")
+	}
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		return "", errors.Wrap(err, "create config file")
+	}
+
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+
+	return path, nil
+}
+
+// promptValue asks for a single value. An empty input keeps the current one
+// and returns an empty string.
+func promptValue(in *bufio.Reader, field wizardField) (string, error) {
+	current := ""
+	if configIsSet(field.key) {
+		current = configString(field.key)
+		if field.secret || field.file {
+			current = "configured"
+		}
+	}
+
+	prompt := field.label
+	if current != "" {
+		prompt += " [" + current + "]"
+	}
+	fmt.Fprint(os.Stderr, prompt+": ")
+
+	var line string
+	if field.secret && term.IsTerminal(int(os.Stdin.Fd())) {
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", errors.Wrapf(err, "read %s", field.label)
+		}
+		line = string(b)
+	} else {
+		var err error
+		line, err = in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", errors.Wrapf(err, "read %s", field.label)
+		}
+	}
+
+	value := strings.TrimSpace(line)
+	if value == "" || !field.file {
+		return value, nil
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "read %s", value)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var (
+	configKeygenCmd = &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate the key pair used to share the config within the team",
+		Long: `Generates a key pair, stores the private key in the config file
+('private_key') and prints the public key. Teammates add the public key to
+the 'recipients' in their config to share the config with you.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if configIsSet("private_key") {
+				pub, err := functions.PublicKey(configValue("private_key"))
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				fmt.Println(pub)
+				return
+			}
+
+			pub, priv, err := functions.GenerateMemberKey()
+			if err != nil {
+				cobra.CheckErr(err)
+			}
+
+			if err := secretStore().Set("private_key", priv); err != nil {
+				cobra.CheckErr(errors.Wrap(err, "store private key"))
+			}
+
+			fmt.Println(pub)
+		},
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configKeygenCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"gopkg.in/yaml.v3"
+)
+
+// sharedKeys are the parts of the config that are shared within the team.
+var sharedKeys = []string{
+	"spaces_bucket",
+	"spaces_region",
+	"spaces_key",
+	"spaces_secret",
+	"credentials",
+	"github_token",
+}
+
+var (
+	shareOutput     string
+	shareRecipients map[string]string
+
+	configShareCmd = &cobra.Command{
+		Use:   "share",
+		Short: "Encrypt the shared parts of the config for the team",
+		Long: `Encrypts the spaces credentials, the client credentials and the github token
+for every teammate in 'recipients' (name to public key, see 'config keygen')
+and writes them to a file that can be committed to a repository. Every
+recipient decrypts it with 'config import' and their own private key.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !configIsSet("private_key") {
+				cobra.CheckErr(errors.New("no private key configured, run: snctl config keygen"))
+			}
+			privateKey := configValue("private_key")
+
+			recipients := viper.GetStringMapString(configKey("recipients"))
+			for name, key := range shareRecipients {
+				recipients[name] = key
+			}
+
+			// always include the own key, so the sender can import it as well
+			own, err := functions.PublicKey(privateKey)
+			if err != nil {
+				cobra.CheckErr(err)
+			}
+
+			found := false
+			for _, key := range recipients {
+				found = found || key == own
+			}
+
+			if !found {
+				name := "me"
+				if u, err := user.Current(); err == nil {
+					name = u.Username
+				}
+				recipients[name] = own
+			}
+
+			values := map[string]string{}
+			for _, key := range sharedKeys {
+				if secretStore().IsSet(key) {
+					values[key] = configValue(key)
+				}
+			}
+
+			shared, err := functions.SealShared(values, privateKey, recipients)
+			if err != nil {
+				cobra.CheckErr(errors.Wrap(err, "encrypt config"))
+			}
+
+			data, err := yaml.Marshal(shared)
+			if err != nil {
+				cobra.CheckErr(errors.Wrap(err, "encode shared config"))
+			}
+
+			if err := os.WriteFile(shareOutput, data, 0644); err != nil {
+				cobra.CheckErr(errors.Wrap(err, "write shared config"))
+			}
+
+			fmt.Printf("shared %d values with %d recipients in %s\n", len(values), len(recipients), shareOutput)
+		},
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configShareCmd)
+	configShareCmd.Flags().StringVar(&shareOutput, "out", "startup_nights.shared.yaml", "File to write the encrypted config to")
+	configShareCmd.Flags().StringToStringVar(&shareRecipients, "recipient", nil, "Additional recipient as name=public-key")
+}
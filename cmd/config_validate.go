@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"golang.org/x/oauth2/google"
+)
+
+// configField describes a key of the config file for 'config validate'.
+type configField struct {
+	key      string
+	required bool
+	check    func(value string) error
+}
+
+var configSchema = []configField{
+	{key: "store", check: validateStore},
+	{key: "credentials", required: true, check: validateCredentials},
+	{key: "spaces_bucket", required: true},
+	{key: "spaces_region", required: true},
+	{key: "spaces_key", required: true},
+	{key: "spaces_secret", required: true},
+	{key: "gmail_token", check: validateToken},
+	{key: "sheets_token", check: validateToken},
+	{key: "drive_token", check: validateToken},
+	{key: "github_token"},
+	{key: "github_url", check: validateURL},
+	{key: "secrets_url", check: validateURL},
+	{key: "sheets_url", check: validateURL},
+	{key: "service_account", check: validateServiceAccount},
+	{key: "private_key", check: validatePrivateKey},
+}
+
+var (
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file for missing or malformed keys",
+		Long: `Checks the config of the selected profile: required keys have to be set and
+tokens, credentials, keys, urls and the secret targets have to be well-formed.
+Exits non-zero if there are problems.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			problems := validateConfig()
+
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+
+			if len(problems) > 0 {
+				cobra.CheckErr(errors.Errorf("%d problem(s) in %s", len(problems), viper.ConfigFileUsed()))
+			}
+
+			fmt.Printf("=> %s is valid\n", viper.ConfigFileUsed())
+		},
+	}
+)
+
+// validateConfig returns a line per missing or malformed key.
+func validateConfig() []string {
+	problems := []string{}
+
+	for _, field := range configSchema {
+		if !configIsSet(field.key) || configString(field.key) == "" {
+			if field.required {
+				problems = append(problems, fmt.Sprintf("%-8s %s", "missing", field.key))
+			}
+			continue
+		}
+
+		value := configString(field.key)
+
+		// the store can't be read before its backend is known to be valid
+		var err error
+		if field.key != "store" {
+			value, err = secretStore().Get(field.key)
+		}
+		if err == nil && field.check != nil {
+			err = field.check(value)
+		}
+
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%-8s %s: %s", "invalid", field.key, err))
+
+			// secrets can't be read without a valid store
+			if field.key == "store" {
+				return problems
+			}
+		}
+	}
+
+	if configIsSet("secrets") {
+		targets := []secretTarget{}
+		if err := viper.UnmarshalKey(configKey("secrets")+".targets", &targets); err != nil {
+			problems = append(problems, fmt.Sprintf("%-8s secrets.targets: %s", "invalid", err))
+		}
+
+		for i, t := range targets {
+			if err := t.validate(); err != nil {
+				problems = append(problems, fmt.Sprintf("%-8s secrets.targets[%d]: %s", "invalid", i, err))
+			}
+		}
+
+		workflows := []workflowTarget{}
+		if err := viper.UnmarshalKey(configKey("secrets")+".workflows", &workflows); err != nil {
+			problems = append(problems, fmt.Sprintf("%-8s secrets.workflows: %s", "invalid", err))
+		}
+
+		for i, w := range workflows {
+			if w.Owner == "" || w.Repo == "" || w.Workflow == "" {
+				problems = append(problems, fmt.Sprintf("%-8s secrets.workflows[%d]: owner, repo and workflow are required", "invalid", i))
+			}
+		}
+	}
+
+	if configIsSet("website") {
+		if _, err := websiteSettings(); err != nil {
+			problems = append(problems, fmt.Sprintf("%-8s website: %s", "invalid", err))
+		}
+	}
+
+	for name, key := range viper.GetStringMapString(configKey("recipients")) {
+		if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 32 {
+			problems = append(problems, fmt.Sprintf("%-8s recipients.%s: not a public key", "invalid", name))
+		}
+	}
+
+	return problems
+}
+
+func validateCredentials(value string) error {
+	_, err := google.ConfigFromJSON([]byte(value))
+	return err
+}
+
+func validateToken(value string) error {
+	token, err := functions.ParseToken(value)
+	if err != nil {
+		return err
+	}
+
+	if token.AccessToken == "" && token.RefreshToken == "" {
+		return errors.New("neither access nor refresh token")
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return errors.New("not an absolute url")
+	}
+
+	return nil
+}
+
+func validateServiceAccount(value string) error {
+	_, err := google.JWTConfigFromJSON([]byte(value))
+	return err
+}
+
+func validatePrivateKey(value string) error {
+	_, err := functions.PublicKey(value)
+	return err
+}
+
+func validateStore(value string) error {
+	if value != "config" && value != "vault" {
+		return errors.Errorf("unknown store backend %q, expected config or vault", value)
+	}
+
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+// importSettings are the settings of the speaker or team import of an
+// edition.
+type importSettings struct {
+	// Name of the edition.
+	Name string `mapstructure:"-"`
+	// DriveFolder contains the images, matched by name (speaker only).
+	DriveFolder string `mapstructure:"drive_folder"`
+	// Prefix of the uploaded images in the bucket (default <edition>/<kind>).
+	Prefix string `mapstructure:"prefix"`
+	Width  int    `mapstructure:"width"`
+	Height int    `mapstructure:"height"`
+	// Sheet is the id of the google sheet the csv is exported from.
+	Sheet string `mapstructure:"sheet"`
+	// MaxDescription is the maximum length of a description (default 600),
+	// checked by --validate.
+	MaxDescription int `mapstructure:"max_description"`
+	// Indent and RootKey are the defaults of --indent and --root-key.
+	Indent  int    `mapstructure:"indent"`
+	RootKey string `mapstructure:"root_key"`
+	// DataFile is the path of the data file in the website repository,
+	// used by --publish.
+	DataFile string `mapstructure:"data_file"`
+	// Columns maps the fields of the import to the columns of the form export.
+	Columns functions.ColumnMapping `mapstructure:"columns"`
+	// Writeback are the headers of the columns the import status is written
+	// to, if the rows are read from a sheet.
+	Writeback statusHeaders `mapstructure:"writeback"`
+}
+
+// statusHeaders are the headers of the status columns, only the status is
+// required.
+type statusHeaders struct {
+	Status    string `mapstructure:"status"`
+	Reason    string `mapstructure:"reason"`
+	Image     string `mapstructure:"image"`
+	Timestamp string `mapstructure:"timestamp"`
+}
+
+// edition holds the settings of one year of startup nights.
+type edition struct {
+	Speaker importSettings `mapstructure:"speaker"`
+	Team    importSettings `mapstructure:"team"`
+}
+
+var (
+	editionName string
+
+	// the settings that were hardcoded before editions became configurable
+	defaultEditions = map[string]edition{
+		"2024": {
+			Speaker: importSettings{DriveFolder: "11Tqb7iAW8QUpqw2TaSu55LqQ-RhrgEWr"},
+		},
+	}
+
+	// requiredFields are the fields the column mapping of an edition has to
+	// contain. The headers of the form export change with every edition, so
+	// there is no default mapping.
+	requiredFields = map[string][]string{
+		"speaker": {"name", "image"},
+		"team":    {"first_name", "linkedin", "image"},
+	}
+)
+
+// addEditionFlags adds the flags to select the edition and to override its
+// image dimensions.
+func addEditionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&editionName, "edition", "", "Edition in the 'editions' config section (default 'edition' from the config or the current year)")
+}
+
+// editionSettings returns the settings of kind (speaker or team) of the
+// selected edition, with defaults for everything that isn't configured. An
+// edition that is neither configured nor one of the defaults is an error,
+// wherever its name came from. --width and --height override the configured dimensions.
+func editionSettings(cmd *cobra.Command, kind string) (importSettings, error) {
+	name := editionName
+	if name == "" {
+		name = configValue("edition")
+	}
+	if name == "" {
+		name = strconv.Itoa(time.Now().Year())
+	}
+
+	e, ok := defaultEditions[name]
+	if key := configKey("editions") + "." + name; viper.IsSet(key) {
+		// the configured settings override the defaults of the edition
+		if err := viper.UnmarshalKey(key, &e); err != nil {
+			return importSettings{}, errors.Wrapf(err, "parse edition %s", name)
+		}
+	} else if !ok {
+		return importSettings{}, errors.Errorf("unknown edition %q, configure it in 'editions' or select another one with --edition", name)
+	}
+
+	s := e.Speaker
+	if kind == "team" {
+		s = e.Team
+	}
+
+	s.Name = name
+	if len(s.Columns) == 0 {
+		return importSettings{}, errors.Errorf("no columns configured for the %s of edition %s, map the headers of the form export in editions.%s.%s.columns", kind, name, name, kind)
+	}
+	for _, field := range requiredFields[kind] {
+		if _, ok := s.Columns[field]; !ok {
+			return importSettings{}, errors.Errorf("the columns of the %s of edition %s don't map the field %q", kind, name, field)
+		}
+	}
+	if s.MaxDescription == 0 {
+		s.MaxDescription = 600
+	}
+	if s.Prefix == "" {
+		s.Prefix = name + "/" + kind
+	}
+	if s.Width == 0 {
+		s.Width = 500
+	}
+	if s.Height == 0 {
+		s.Height = 500
+	}
+
+	if cmd.Flags().Changed("width") {
+		s.Width = targetWidth
+	}
+	if cmd.Flags().Changed("height") {
+		s.Height = targetHeight
+	}
+
+	return s, nil
+}
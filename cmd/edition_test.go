@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestEditionSettings(t *testing.T) {
+	columns := map[string]interface{}{
+		"name":  map[string]interface{}{"header": "Name", "required": true},
+		"image": map[string]interface{}{"header": "Image", "required": true},
+	}
+	configured := map[string]interface{}{"speaker": map[string]interface{}{"drive_folder": "folder", "columns": columns}}
+
+	tests := []struct {
+		name    string
+		flag    string
+		config  map[string]interface{}
+		want    string
+		folder  string
+		wantErr bool
+	}{
+		{
+			name:   "flag",
+			flag:   "2025",
+			config: map[string]interface{}{"editions": map[string]interface{}{"2025": configured}},
+			want:   "2025",
+			folder: "folder",
+		},
+		{
+			name:   "config",
+			config: map[string]interface{}{"edition": "2025", "editions": map[string]interface{}{"2025": configured}},
+			want:   "2025",
+			folder: "folder",
+		},
+		{
+			// the configured columns are added to the default settings
+			name:   "default edition",
+			flag:   "2024",
+			config: map[string]interface{}{"editions": map[string]interface{}{"2024": map[string]interface{}{"speaker": map[string]interface{}{"columns": columns}}}},
+			want:   "2024",
+			folder: defaultEditions["2024"].Speaker.DriveFolder,
+		},
+		{
+			name:    "default edition without columns",
+			flag:    "2024",
+			wantErr: true,
+		},
+		{
+			name: "required field not mapped",
+			flag: "2025",
+			config: map[string]interface{}{"editions": map[string]interface{}{"2025": map[string]interface{}{"speaker": map[string]interface{}{
+				"columns": map[string]interface{}{"name": map[string]interface{}{"header": "Name"}},
+			}}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			flag:    "2052",
+			wantErr: true,
+		},
+		{
+			name:    "unknown edition in config",
+			config:  map[string]interface{}{"edition": "2052", "editions": map[string]interface{}{"2025": configured}},
+			wantErr: true,
+		},
+		{
+			// the current year is neither configured nor a default
+			name:    "nothing configured",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+
+			editionName = tt.flag
+			t.Cleanup(func() { editionName = "" })
+
+			s, err := editionSettings(&cobra.Command{}, "speaker")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("editionSettings() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if s.Name != tt.want || s.DriveFolder != tt.folder {
+				t.Errorf("edition %q with folder %q, want %q with %q", s.Name, s.DriveFolder, tt.want, tt.folder)
+			}
+			if s.Prefix != tt.want+"/speaker" {
+				t.Errorf("prefix = %q, want the default", s.Prefix)
+			}
+		})
+	}
+}
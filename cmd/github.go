@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// newGithubClient returns a client authenticated with the configured
+// 'github_token'. The api can be redirected with 'github_url', for example to
+// a local fake.
+func newGithubClient() *github.Client {
+	if !configIsSet("github_token") {
+		cobra.CheckErr(errors.New("no github token configured"))
+	}
+
+	client := github.NewClient(nil).WithAuthToken(configValue("github_token"))
+
+	if base := configValue("github_url"); base != "" {
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+
+		u, err := url.Parse(base)
+		if err != nil {
+			cobra.CheckErr(errors.Wrap(err, "parse github url"))
+		}
+
+		client.BaseURL = u
+	}
+
+	return client
+}
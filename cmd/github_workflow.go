@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/pkg/errors"
+)
+
+// logTailLines is the number of log lines printed for a failed job.
+const logTailLines = 30
+
+// dispatchTimeout is how long to wait for a dispatched run to show up.
+const dispatchTimeout = 2 * time.Minute
+
+// clockSkew is the difference between the local clock and the one of github
+// that is tolerated when matching the dispatched run by its creation time.
+const clockSkew = time.Minute
+
+// runWorkflows dispatches the workflows and, if follow is set, follows their
+// runs. A run that didn't succeed or wasn't completed within the timeout
+// fails it after all workflows were dispatched.
+func runWorkflows(ctx context.Context, client *github.Client, workflows []workflowTarget, follow bool, interval, timeout time.Duration) error {
+	failed := 0
+	for _, w := range workflows {
+		ok, err := runWorkflow(ctx, client, w, follow, interval, timeout)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("%d workflow run(s) did not succeed", failed)
+	}
+
+	return nil
+}
+
+func runWorkflow(ctx context.Context, client *github.Client, w workflowTarget, follow bool, interval, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	run, err := dispatchWorkflow(ctx, client, w, interval)
+	if err != nil {
+		return false, errors.Wrapf(err, "dispatch %s of %s/%s", w.Workflow, w.Owner, w.Repo)
+	}
+
+	if !follow {
+		fmt.Printf("=> run %d: %s\n", run.GetID(), run.GetHTMLURL())
+		return true, nil
+	}
+
+	conclusion, err := followWorkflowRun(ctx, client, w.Owner, w.Repo, run, interval)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Printf("=> run %d not completed within %s: %s\n", run.GetID(), timeout, run.GetHTMLURL())
+		return false, nil
+
+	case err != nil:
+		return false, errors.Wrapf(err, "follow %s of %s/%s", w.Workflow, w.Owner, w.Repo)
+	}
+
+	return conclusion == "success", nil
+}
+
+// dispatchWorkflow triggers the workflow and returns the run that was created
+// by the dispatch. The dispatch endpoint doesn't return the run, so the runs
+// that existed before are remembered and the new one is polled for. Runs
+// created before the dispatch are ignored, and if several new runs show up
+// (e.g. someone else dispatched the workflow at the same time) it fails
+// instead of guessing.
+func dispatchWorkflow(ctx context.Context, client *github.Client, w workflowTarget, interval time.Duration) (*github.WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Event:       "workflow_dispatch",
+		Branch:      w.Ref,
+		ListOptions: github.ListOptions{PerPage: 20},
+	}
+
+	runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, w.Owner, w.Repo, w.Workflow, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "list workflow runs")
+	}
+
+	known := map[int64]bool{}
+	for _, run := range runs.WorkflowRuns {
+		known[run.GetID()] = true
+	}
+
+	dispatched := time.Now().Add(-clockSkew)
+
+	if _, err := client.Actions.CreateWorkflowDispatchEventByFileName(ctx, w.Owner, w.Repo, w.Workflow,
+		github.CreateWorkflowDispatchEventRequest{
+			Ref: w.Ref,
+		},
+	); err != nil {
+		return nil, errors.Wrap(err, "trigger workflow run")
+	}
+
+	fmt.Printf("=> dispatched %s on %s of %s/%s\n", w.Workflow, w.Ref, w.Owner, w.Repo)
+
+	// the run shows up with a short delay
+	deadline := time.Now().Add(dispatchTimeout)
+	for time.Now().Before(deadline) {
+		runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, w.Owner, w.Repo, w.Workflow, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "list workflow runs")
+		}
+
+		created := []*github.WorkflowRun{}
+		for _, run := range runs.WorkflowRuns {
+			if !known[run.GetID()] && !run.GetCreatedAt().Before(dispatched) {
+				created = append(created, run)
+			}
+		}
+
+		switch len(created) {
+		case 0:
+		case 1:
+			return created[0], nil
+		default:
+			urls := []string{}
+			for _, run := range created {
+				urls = append(urls, run.GetHTMLURL())
+			}
+			return nil, errors.Errorf("several runs were dispatched at the same time, follow them in the browser: %s", strings.Join(urls, ", "))
+		}
+
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errors.Errorf("dispatched run did not show up within %s", dispatchTimeout)
+}
+
+// followWorkflowRun polls the run until it is completed and prints the
+// progress of its steps. For failed jobs the tail of the log is printed. It
+// returns the conclusion of the run, or the error of the context if its
+// deadline passed before.
+func followWorkflowRun(ctx context.Context, client *github.Client, owner, repo string, run *github.WorkflowRun, interval time.Duration) (string, error) {
+	fmt.Printf("=> following run %d: %s\n", run.GetID(), run.GetHTMLURL())
+
+	seen := map[string]string{}
+
+	for {
+		run, _, err := client.Actions.GetWorkflowRunByID(ctx, owner, repo, run.GetID())
+		if err != nil {
+			return "", errors.Wrap(err, "get workflow run")
+		}
+
+		jobs, _, err := client.Actions.ListWorkflowJobs(ctx, owner, repo, run.GetID(), &github.ListWorkflowJobsOptions{
+			ListOptions: github.ListOptions{PerPage: 100},
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "list workflow jobs")
+		}
+
+		for _, job := range jobs.Jobs {
+			for _, step := range job.Steps {
+				state := step.GetStatus()
+				if step.GetConclusion() != "" {
+					state += " (" + step.GetConclusion() + ")"
+				}
+
+				key := fmt.Sprintf("%d/%d", job.GetID(), step.GetNumber())
+				if seen[key] == state {
+					continue
+				}
+
+				seen[key] = state
+				fmt.Printf("   %s / %s: %s\n", job.GetName(), step.GetName(), state)
+			}
+		}
+
+		if run.GetStatus() == "completed" {
+			for _, job := range jobs.Jobs {
+				if job.GetConclusion() == "failure" {
+					printJobLogTail(ctx, client, owner, repo, job)
+				}
+			}
+
+			fmt.Printf("=> run finished: %s\n", run.GetConclusion())
+			return run.GetConclusion(), nil
+		}
+
+		if err := sleep(ctx, interval); err != nil {
+			return "", err
+		}
+	}
+}
+
+// printJobLogTail prints the last lines of the job log. Failing to get the
+// log is only reported, the conclusion of the run is what matters.
+func printJobLogTail(ctx context.Context, client *github.Client, owner, repo string, job *github.WorkflowJob) {
+	fmt.Printf("=> log of failed job %s:\n", job.GetName())
+
+	u, _, err := client.Actions.GetWorkflowJobLogs(ctx, owner, repo, job.GetID(), 1)
+	if err != nil {
+		fmt.Printf("   failed to get log: %v\n", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		fmt.Printf("   failed to get log: %v\n", err)
+		return
+	}
+
+	// the log url is pre-signed, the github token must not be sent along
+	res, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		fmt.Printf("   failed to get log: %v\n", err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		fmt.Printf("   failed to get log: %s\n", res.Status)
+		return
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > logTailLines {
+			lines = lines[1:]
+		}
+	}
+
+	for _, line := range lines {
+		fmt.Println("   " + strings.TrimRight(line, "\r"))
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
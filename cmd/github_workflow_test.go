@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fakeActions is a minimal fake of the github actions api endpoints used to
+// dispatch and follow a workflow run.
+type fakeActions struct {
+	url string
+
+	mu sync.Mutex
+	// dispatched is set once the workflow was dispatched, the runs created
+	// by it are listed from then on.
+	dispatched bool
+	// created are the runs that show up after the dispatch.
+	created []string
+	// polls is the number of times the run was polled, and completeAfter
+	// the poll from which on it is completed with conclusion. A run with
+	// completeAfter 0 is never completed.
+	polls         int
+	completeAfter int
+	conclusion    string
+}
+
+func (f *fakeActions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const repo = "/repos/sn/deploy"
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch path := r.URL.Path; {
+	case path == repo+"/actions/workflows/deploy.yml/runs":
+		// an old run that is listed before and after the dispatch and one
+		// that wasn't on the first page but was created long before
+		runs := []string{`{"id":1,"created_at":"2024-01-01T00:00:00Z"}`}
+		if f.dispatched {
+			runs = append(runs, `{"id":3,"created_at":"2024-01-02T00:00:00Z"}`)
+			runs = append(runs, f.created...)
+		}
+		fmt.Fprintf(w, `{"total_count":%d,"workflow_runs":[%s]}`, len(runs), strings.Join(runs, ","))
+
+	case path == repo+"/actions/workflows/deploy.yml/dispatches":
+		f.dispatched = true
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == repo+"/actions/runs/2":
+		f.polls++
+		if f.completeAfter > 0 && f.polls >= f.completeAfter {
+			fmt.Fprintf(w, `{"id":2,"status":"completed","conclusion":%q}`, f.conclusion)
+			return
+		}
+		fmt.Fprint(w, `{"id":2,"status":"in_progress"}`)
+
+	case path == repo+"/actions/runs/2/jobs":
+		step := `{"number":1,"name":"Deploy","status":"in_progress"}`
+		job := `"id":10,"name":"deploy","status":"in_progress"`
+		if f.completeAfter > 0 && f.polls >= f.completeAfter {
+			step = fmt.Sprintf(`{"number":1,"name":"Deploy","status":"completed","conclusion":%q}`, f.conclusion)
+			job = fmt.Sprintf(`"id":10,"name":"deploy","status":"completed","conclusion":%q`, f.conclusion)
+		}
+		fmt.Fprintf(w, `{"total_count":1,"jobs":[{%s,"steps":[{"number":0,"name":"Checkout","status":"completed","conclusion":"success"},%s]}]}`, job, step)
+
+	case path == repo+"/actions/jobs/10/logs":
+		http.Redirect(w, r, f.url+"/logs/10", http.StatusFound)
+
+	case path == "/logs/10":
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "the github token was sent to the log url", http.StatusForbidden)
+			return
+		}
+		for i := 1; i <= 40; i++ {
+			fmt.Fprintf(w, "line %d\r\n", i)
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// newRun returns a run as listed after the dispatch.
+func newRun(id int, created time.Time) string {
+	return fmt.Sprintf(`{"id":%d,"html_url":"https://github.com/sn/deploy/actions/runs/%d","created_at":%q}`, id, id, created.UTC().Format(time.RFC3339))
+}
+
+// runTestWorkflow runs the deploy workflow against the fake and returns the
+// error and what was printed.
+func runTestWorkflow(t *testing.T, fake *fakeActions, timeout time.Duration) (string, error) {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("github_token", "token")
+	viper.Set("github_url", server.URL)
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWorkflows(context.Background(), newGithubClient(),
+			[]workflowTarget{{Owner: "sn", Repo: "deploy", Workflow: "deploy.yml", Ref: "main"}},
+			true, time.Millisecond, timeout)
+	})
+
+	return out, err
+}
+
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+
+	f()
+	w.Close()
+
+	return <-done
+}
+
+func TestRunWorkflowsSuccess(t *testing.T) {
+	fake := &fakeActions{created: []string{newRun(2, time.Now())}, completeAfter: 3, conclusion: "success"}
+
+	out, err := runTestWorkflow(t, fake, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"=> following run 2: https://github.com/sn/deploy/actions/runs/2\n",
+		"   deploy / Checkout: completed (success)\n",
+		"   deploy / Deploy: in_progress\n",
+		"   deploy / Deploy: completed (success)\n",
+		"=> run finished: success\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	// a step is only printed when its state changes
+	if n := strings.Count(out, "deploy / Deploy: in_progress"); n != 1 {
+		t.Errorf("printed the running step %d times, want once:\n%s", n, out)
+	}
+	if strings.Contains(out, "log of failed job") {
+		t.Errorf("printed a log for a successful run:\n%s", out)
+	}
+}
+
+func TestRunWorkflowsFailure(t *testing.T) {
+	fake := &fakeActions{created: []string{newRun(2, time.Now())}, completeAfter: 1, conclusion: "failure"}
+
+	out, err := runTestWorkflow(t, fake, time.Minute)
+	if err == nil {
+		t.Fatal("a failed run succeeded")
+	}
+
+	if !strings.Contains(out, "=> log of failed job deploy:\n   line 11\n") || !strings.Contains(out, "   line 40\n") {
+		t.Errorf("output doesn't contain the last %d lines of the log:\n%s", logTailLines, out)
+	}
+	if strings.Contains(out, "line 10\n") {
+		t.Errorf("output contains more than the last %d lines of the log:\n%s", logTailLines, out)
+	}
+}
+
+// A run that isn't completed in time fails the command instead of hanging.
+func TestRunWorkflowsTimeout(t *testing.T) {
+	fake := &fakeActions{created: []string{newRun(2, time.Now())}}
+
+	out, err := runTestWorkflow(t, fake, 50*time.Millisecond)
+	if err == nil {
+		t.Fatal("a run that never completed succeeded")
+	}
+
+	if !strings.Contains(out, "=> run 2 not completed within 50ms") {
+		t.Errorf("output doesn't report the timeout:\n%s", out)
+	}
+}
+
+// If someone else dispatched the workflow at the same time, the run to follow
+// is ambiguous.
+func TestRunWorkflowsConcurrentDispatch(t *testing.T) {
+	fake := &fakeActions{created: []string{newRun(2, time.Now()), newRun(4, time.Now())}, completeAfter: 1, conclusion: "success"}
+
+	if _, err := runTestWorkflow(t, fake, time.Minute); err == nil {
+		t.Fatal("followed one of several new runs")
+	}
+	if fake.polls != 0 {
+		t.Errorf("polled a run %d times, want none", fake.polls)
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// profile is the selected profile, see configKey.
+var profile string
+
+// configKey returns the viper key under which key is read. Values in the
+// section of the selected profile ('profiles.<name>.<key>') override the top
+// level values, so shared settings only have to be configured once.
+func configKey(key string) string {
+	if profile != "" {
+		if scoped := "profiles." + profile + "." + key; viper.IsSet(scoped) {
+			return scoped
+		}
+	}
+
+	return key
+}
+
+// writeKey returns the viper key under which key is written. With a profile
+// selected, values are always written to its section.
+func writeKey(key string) string {
+	if profile != "" {
+		return "profiles." + profile + "." + key
+	}
+
+	return key
+}
+
+func configIsSet(key string) bool {
+	return viper.IsSet(configKey(key))
+}
+
+func configString(key string) string {
+	return viper.GetString(configKey(key))
+}
+
+// setConfig writes value to the config file.
+func setConfig(key string, value interface{}) error {
+	viper.Set(writeKey(key), value)
+	if err := viper.WriteConfig(); err != nil {
+		return errors.Wrapf(err, "write %s to config", key)
+	}
+
+	return nil
+}
+
+// profileNames returns the names of all profiles in the config file.
+func profileNames() []string {
+	names := []string{}
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// allConfigKeys returns the top level key and the key in every profile, for
+// commands that work on the whole file.
+func allConfigKeys(key string) []string {
+	keys := []string{key}
+	for _, name := range profileNames() {
+		keys = append(keys, "profiles."+name+"."+key)
+	}
+
+	return keys
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// referencePrefixes are the prefixes of values that refer to a secret kept
+// outside of the config file:
+//
+//	env:SPACES_SECRET         environment variable
+//	file:/run/secrets/spaces  content of a file
+//	exec:pass show sn/spaces  output of a command (run with sh -c)
+var referencePrefixes = []string{"env:", "file:", "exec:"}
+
+// resolved caches the resolved references, so commands only run once per
+// invocation.
+var resolved = map[string]string{}
+
+func isReference(value string) bool {
+	for _, prefix := range referencePrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveReference returns the value a reference points to. Other values are
+// returned as they are.
+func resolveReference(value string) (string, error) {
+	if !isReference(value) {
+		return value, nil
+	}
+
+	if v, ok := resolved[value]; ok {
+		return v, nil
+	}
+
+	kind, target, _ := strings.Cut(value, ":")
+
+	var v string
+	switch kind {
+	case "env":
+		var ok bool
+		if v, ok = os.LookupEnv(target); !ok {
+			return "", errors.Errorf("environment variable %s is not set", target)
+		}
+
+	case "file":
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return "", errors.Wrap(err, "read secret file")
+		}
+		v = string(data)
+
+	case "exec":
+		out := &bytes.Buffer{}
+		cmd := exec.Command("sh", "-c", target)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", errors.Wrapf(err, "run %q", target)
+		}
+		v = out.String()
+	}
+
+	v = strings.TrimSpace(v)
+	resolved[value] = v
+
+	return v, nil
+}
+
+// checkWritable makes sure that writing key doesn't replace a reference with
+// the value itself.
+func checkWritable(key string) error {
+	if value := viper.GetString(writeKey(key)); isReference(value) {
+		return errors.Errorf("%s refers to %s, update the value there", key, value)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"io/fs"
 	"os"
 
 	"github.com/pkg/errors"
@@ -8,10 +9,28 @@ import (
 	"github.com/spf13/viper"
 )
 
-var rootCmd = &cobra.Command{
-	Use:   "functions",
-	Short: "CLI app to handle the digitalocean functions and related functionality",
-}
+// configAnnotation marks commands that work without a config file
+// ("optional").
+const configAnnotation = "config"
+
+var (
+	cfgFile string
+
+	rootCmd = &cobra.Command{
+		Use:   "functions",
+		Short: "CLI app to handle the digitalocean functions and related functionality",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// failing checks are not a usage error
+			cmd.SilenceUsage = true
+
+			if err := loadConfig(cmd); err != nil {
+				return err
+			}
+
+			return checkScopes(cmd)
+		},
+	}
+)
 
 func Execute() {
 	err := rootCmd.Execute()
@@ -20,18 +39,48 @@ func Execute() {
 	}
 }
 
-func init() {
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.functions.yaml)")
-	viper.SetConfigName("startup_nights")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("$HOME/.config/")
-	viper.AddConfigPath(".")
+// loadConfig reads the config file for the commands that need it, so help
+// and completion work on machines without a config.
+func loadConfig(cmd *cobra.Command) error {
+	mode := cmd.Annotations[configAnnotation]
+	if cmd.Name() == "help" || (cmd.HasParent() && cmd.Parent().Name() == "completion") {
+		return nil
+	}
+
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.SetConfigName("startup_nights")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath("$HOME/.config/")
+		viper.AddConfigPath(".")
+	}
 
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			cobra.CheckErr(errors.Wrap(err, "look for config file"))
-		} else {
-			cobra.CheckErr(errors.Wrap(err, "read config file"))
+		var notFound viper.ConfigFileNotFoundError
+		missing := errors.As(err, &notFound) || errors.Is(err, fs.ErrNotExist)
+
+		switch {
+		case missing && mode == "optional":
+		case missing:
+			return errors.Errorf("%s, create one with: snctl config init", err)
+		default:
+			return errors.Wrap(err, "read config file")
 		}
 	}
+
+	if profile == "" {
+		profile = viper.GetString("profile")
+	}
+
+	if profile != "" && !viper.IsSet("profiles."+profile) && mode != "optional" {
+		return errors.Errorf("unknown profile %q", profile)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default is $HOME/.config/startup_nights.yaml or ./startup_nights.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile in the config file whose values override the top level ones (default 'profile' from the config)")
 }
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/sheets/v4"
+)
+
+// functionsScopes are the scopes needed by the digitalocean functions, which
+// get the gmail and sheets tokens through the github secrets. They can be
+// selected as "functions" in 'token update --for'.
+var functionsScopes = map[string][]string{
+	"gmail":  {gmail.GmailComposeScope},
+	"sheets": {sheets.SpreadsheetsScope},
+}
+
+// commandScopes is the registry of the google scopes every command needs,
+// per token (gmail, sheets or drive). Commands declare them in their init
+// function with requireScopes.
+var commandScopes = map[*cobra.Command]map[string][]string{}
+
+// broaderScopes lists the scopes that include the access of a scope.
+var broaderScopes = map[string][]string{
+	drive.DriveReadonlyScope:         {drive.DriveScope},
+	sheets.SpreadsheetsReadonlyScope: {sheets.SpreadsheetsScope, drive.DriveScope},
+}
+
+// conditionalScope are scopes a command only needs in some cases, e.g. if a
+// flag is set.
+type conditionalScope struct {
+	token  string
+	scopes []string
+	needed func(cmd *cobra.Command) bool
+}
+
+var conditionalScopes = map[*cobra.Command][]conditionalScope{}
+
+func requireScopes(cmd *cobra.Command, token string, scopes ...string) {
+	if commandScopes[cmd] == nil {
+		commandScopes[cmd] = map[string][]string{}
+	}
+
+	commandScopes[cmd][token] = append(commandScopes[cmd][token], scopes...)
+}
+
+// requireScopesIf declares scopes that the command only needs if needed
+// returns true. They are always requested by 'token update --for'.
+func requireScopesIf(cmd *cobra.Command, needed func(cmd *cobra.Command) bool, token string, scopes ...string) {
+	conditionalScopes[cmd] = append(conditionalScopes[cmd], conditionalScope{token: token, scopes: scopes, needed: needed})
+}
+
+// requiredScopes returns the scopes the command needs per token. With all
+// set, conditional scopes are included whether they are needed or not.
+func requiredScopes(cmd *cobra.Command, all bool) map[string][]string {
+	required := map[string][]string{}
+	for token, scopes := range commandScopes[cmd] {
+		required[token] = append(required[token], scopes...)
+	}
+
+	for _, c := range conditionalScopes[cmd] {
+		if all || c.needed(cmd) {
+			required[c.token] = append(required[c.token], c.scopes...)
+		}
+	}
+
+	return required
+}
+
+// commandName is the command path without the name of the binary.
+func commandName(cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+}
+
+// scopedCommands returns the scope requirements by command name, including
+// the digitalocean functions.
+func scopedCommands() map[string]map[string][]string {
+	commands := map[string]map[string][]string{
+		"functions": functionsScopes,
+	}
+
+	for cmd := range commandScopes {
+		commands[commandName(cmd)] = requiredScopes(cmd, true)
+	}
+
+	for cmd := range conditionalScopes {
+		commands[commandName(cmd)] = requiredScopes(cmd, true)
+	}
+
+	return commands
+}
+
+// scopesFor returns the union of the scopes the selected commands need on the
+// token. Without a selection all commands are taken into account.
+func scopesFor(token string, selected []string) ([]string, error) {
+	commands := scopedCommands()
+
+	if len(selected) == 0 {
+		for name := range commands {
+			selected = append(selected, name)
+		}
+	}
+
+	union := map[string]bool{}
+	for _, name := range selected {
+		scopes, ok := commands[name]
+		if !ok {
+			known := []string{}
+			for name := range commands {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+
+			return nil, errors.Errorf("unknown command %q, expected one of: %s", name, strings.Join(known, ", "))
+		}
+
+		for _, scope := range scopes[token] {
+			union[scope] = true
+		}
+	}
+
+	if len(union) == 0 {
+		return nil, errors.Errorf("none of the selected commands needs the %s token", token)
+	}
+
+	scopes := []string{}
+	for scope := range union {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	return scopes, nil
+}
+
+// checkScopes makes sure that the stored tokens were granted the scopes the
+// command declared, before the command starts working. Tokens stored before
+// the granted scopes were recorded can't be checked and only cause a warning.
+func checkScopes(cmd *cobra.Command) error {
+	required := requiredScopes(cmd, false)
+	if len(required) == 0 {
+		return nil
+	}
+
+	// service accounts get the scopes they ask for, as long as they are
+	// allowed to access the files
+	if configIsSet("service_account") {
+		return nil
+	}
+
+	name := commandName(cmd)
+	tokens := []string{}
+	for token := range required {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	for _, token := range tokens {
+		consent := fmt.Sprintf("snctl token update --%s --for '%s'", token, name)
+
+		key := token + "_token"
+		if !secretStore().IsSet(key) {
+			return errors.Errorf("%s needs a %s token, run: %s", name, token, consent)
+		}
+
+		data, err := secretStore().Get(key)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", key)
+		}
+
+		stored, err := functions.ParseToken(data)
+		if err != nil {
+			return errors.Wrapf(err, "parse %s", key)
+		}
+
+		granted := stored.Scopes()
+		if len(granted) == 0 {
+			fmt.Fprintf(os.Stderr, "warning: granted scopes of the %s token are unknown, renew it if %s fails: %s\n", token, name, consent)
+			continue
+		}
+
+		missing := missingScopes(granted, required[token])
+		if len(missing) > 0 {
+			return errors.Errorf("the %s token lacks the scopes %s needs (%s), run: %s", token, name, strings.Join(missing, ", "), consent)
+		}
+	}
+
+	return nil
+}
+
+func missingScopes(granted, required []string) []string {
+	has := map[string]bool{}
+	for _, scope := range granted {
+		has[scope] = true
+	}
+
+	missing := []string{}
+	for _, scope := range required {
+		if has[scope] {
+			continue
+		}
+
+		covered := false
+		for _, broader := range broaderScopes[scope] {
+			covered = covered || has[broader]
+		}
+
+		if !covered {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
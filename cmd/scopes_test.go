@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		granted  []string
+		required []string
+		want     []string
+	}{
+		{nil, []string{drive.DriveReadonlyScope}, []string{drive.DriveReadonlyScope}},
+		{[]string{drive.DriveReadonlyScope}, []string{drive.DriveReadonlyScope}, []string{}},
+		{[]string{drive.DriveScope}, []string{drive.DriveReadonlyScope, sheets.SpreadsheetsReadonlyScope}, []string{}},
+		{[]string{sheets.SpreadsheetsScope}, []string{sheets.SpreadsheetsReadonlyScope, sheets.SpreadsheetsScope}, []string{}},
+		{[]string{sheets.SpreadsheetsReadonlyScope}, []string{sheets.SpreadsheetsScope}, []string{sheets.SpreadsheetsScope}},
+		{[]string{gmail.GmailComposeScope}, []string{gmail.GmailComposeScope, drive.DriveReadonlyScope}, []string{drive.DriveReadonlyScope}},
+	}
+
+	for _, tt := range tests {
+		if got := missingScopes(tt.granted, tt.required); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("missingScopes(%v, %v) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
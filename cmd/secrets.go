@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Handle github actions secrets",
+	Long: `The secrets that are kept in sync are configured in the 'secrets' section of
+the config file:
+
+secrets:
+  targets:
+    - owner: startup-nights
+      repo: functions
+      scope: environment   # repo, environment or org
+      environment: prod
+      name: GMAIL
+      key: gmail_token     # config key the value is read from
+    - owner: startup-nights
+      scope: org
+      visibility: selected # all, private (default) or selected
+      repositories: [functions, website]
+      name: SHEETS
+      key: sheets_token
+  workflows:
+    - owner: startup-nights
+      repo: functions
+      workflow: deploy.yml
+      ref: main
+
+Without a 'secrets' section the gmail and sheets tokens are synced to the
+'prod' environment of startup-nights/functions.`,
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+}
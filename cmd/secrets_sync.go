@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	secretCreated   = "created"
+	secretUpdated   = "updated"
+	secretUnchanged = "unchanged"
+)
+
+var (
+	secretsSyncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Push the configured values to the github actions secrets",
+		Long: `Pushes the value of every configured target to github. A digest of the pushed
+value is kept in the config file ('secrets_state'), so secrets whose value
+didn't change since the last sync are reported as unchanged and not pushed
+again.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := syncSecrets(cmd.Context(), newGithubClient(), secretTargets()); err != nil {
+				cobra.CheckErr(err)
+			}
+		},
+	}
+)
+
+// secretTarget is a single github actions secret that is kept in sync with a
+// value from the config.
+type secretTarget struct {
+	Owner       string `mapstructure:"owner"`
+	Repo        string `mapstructure:"repo"`
+	Scope       string `mapstructure:"scope"`
+	Environment string `mapstructure:"environment"`
+	Name        string `mapstructure:"name"`
+	Key         string `mapstructure:"key"`
+	// Visibility of org secrets: all, private or selected (default private).
+	Visibility string `mapstructure:"visibility"`
+	// Repositories of the organization that can access an org secret with
+	// visibility selected.
+	Repositories []string `mapstructure:"repositories"`
+}
+
+// workflowTarget is a workflow that is dispatched after secrets changed.
+type workflowTarget struct {
+	Owner    string `mapstructure:"owner"`
+	Repo     string `mapstructure:"repo"`
+	Workflow string `mapstructure:"workflow"`
+	Ref      string `mapstructure:"ref"`
+}
+
+// the targets that were hardcoded before they became configurable
+var (
+	defaultSecretTargets = []secretTarget{
+		{Owner: "startup-nights", Repo: "functions", Scope: "environment", Environment: "prod", Name: "GMAIL", Key: "gmail_token"},
+		{Owner: "startup-nights", Repo: "functions", Scope: "environment", Environment: "prod", Name: "SHEETS", Key: "sheets_token"},
+	}
+	defaultWorkflows = []workflowTarget{
+		{Owner: "startup-nights", Repo: "functions", Workflow: "deploy.yml", Ref: "main"},
+	}
+)
+
+func secretTargets() []secretTarget {
+	if !configIsSet("secrets") {
+		return defaultSecretTargets
+	}
+
+	targets := []secretTarget{}
+	if err := viper.UnmarshalKey(configKey("secrets")+".targets", &targets); err != nil {
+		cobra.CheckErr(errors.Wrap(err, "parse secret targets"))
+	}
+
+	for i := range targets {
+		if err := targets[i].validate(); err != nil {
+			cobra.CheckErr(errors.Wrapf(err, "secret target %d", i+1))
+		}
+	}
+
+	return targets
+}
+
+func secretWorkflows() []workflowTarget {
+	if !configIsSet("secrets") {
+		return defaultWorkflows
+	}
+
+	workflows := []workflowTarget{}
+	if err := viper.UnmarshalKey(configKey("secrets")+".workflows", &workflows); err != nil {
+		cobra.CheckErr(errors.Wrap(err, "parse secret workflows"))
+	}
+
+	for i, w := range workflows {
+		if w.Owner == "" || w.Repo == "" || w.Workflow == "" {
+			cobra.CheckErr(errors.Errorf("workflow %d: owner, repo and workflow are required", i+1))
+		}
+
+		if w.Ref == "" {
+			workflows[i].Ref = "main"
+		}
+	}
+
+	return workflows
+}
+
+func (t secretTarget) validate() error {
+	if t.Owner == "" || t.Name == "" || t.Key == "" {
+		return errors.New("owner, name and key are required")
+	}
+
+	switch t.Scope {
+	case "repo":
+		if t.Repo == "" {
+			return errors.New("repo secrets require a repo")
+		}
+
+	case "environment":
+		if t.Repo == "" || t.Environment == "" {
+			return errors.New("environment secrets require a repo and an environment")
+		}
+
+	case "org":
+		switch t.Visibility {
+		case "", "all", "private":
+			if len(t.Repositories) > 0 {
+				return errors.New("repositories require visibility selected")
+			}
+
+		case "selected":
+			if len(t.Repositories) == 0 {
+				return errors.New("visibility selected requires repositories")
+			}
+
+		default:
+			return errors.Errorf("unknown visibility %q, expected all, private or selected", t.Visibility)
+		}
+
+	default:
+		return errors.Errorf("unknown scope %q, expected repo, environment or org", t.Scope)
+	}
+
+	return nil
+}
+
+func (t secretTarget) String() string {
+	switch t.Scope {
+	case "environment":
+		return fmt.Sprintf("%s/%s (env %s) %s", t.Owner, t.Repo, t.Environment, t.Name)
+	case "org":
+		return fmt.Sprintf("%s (org) %s", t.Owner, t.Name)
+	default:
+		return fmt.Sprintf("%s/%s %s", t.Owner, t.Repo, t.Name)
+	}
+}
+
+// stateKey identifies the target in 'secrets_state'. Dots would be read as
+// nested keys by viper.
+func (t secretTarget) stateKey() string {
+	parts := []string{t.Scope, t.Owner}
+	switch t.Scope {
+	case "repo":
+		parts = append(parts, t.Repo)
+	case "environment":
+		parts = append(parts, t.Repo, t.Environment)
+	}
+
+	key := strings.ToLower(strings.Join(append(parts, t.Name), "/"))
+	return strings.ReplaceAll(key, ".", "%2E")
+}
+
+// secretAPI abstracts the scope specific github endpoints.
+type secretAPI struct {
+	publicKey func(ctx context.Context) (*github.PublicKey, *github.Response, error)
+	get       func(ctx context.Context) (*github.Secret, *github.Response, error)
+	put       func(ctx context.Context, secret *github.EncryptedSecret) (*github.Response, error)
+}
+
+func secretEndpoints(ctx context.Context, client *github.Client, t secretTarget) (secretAPI, error) {
+	switch t.Scope {
+	case "repo":
+		return secretAPI{
+			publicKey: func(ctx context.Context) (*github.PublicKey, *github.Response, error) {
+				return client.Actions.GetRepoPublicKey(ctx, t.Owner, t.Repo)
+			},
+			get: func(ctx context.Context) (*github.Secret, *github.Response, error) {
+				return client.Actions.GetRepoSecret(ctx, t.Owner, t.Repo, t.Name)
+			},
+			put: func(ctx context.Context, secret *github.EncryptedSecret) (*github.Response, error) {
+				return client.Actions.CreateOrUpdateRepoSecret(ctx, t.Owner, t.Repo, secret)
+			},
+		}, nil
+
+	case "environment":
+		// environment secrets are addressed by the repository id
+		repo, _, err := client.Repositories.Get(ctx, t.Owner, t.Repo)
+		if err != nil {
+			return secretAPI{}, errors.Wrapf(err, "get repository %s/%s", t.Owner, t.Repo)
+		}
+		id := int(repo.GetID())
+
+		return secretAPI{
+			publicKey: func(ctx context.Context) (*github.PublicKey, *github.Response, error) {
+				return client.Actions.GetEnvPublicKey(ctx, id, t.Environment)
+			},
+			get: func(ctx context.Context) (*github.Secret, *github.Response, error) {
+				return client.Actions.GetEnvSecret(ctx, id, t.Environment, t.Name)
+			},
+			put: func(ctx context.Context, secret *github.EncryptedSecret) (*github.Response, error) {
+				return client.Actions.CreateOrUpdateEnvSecret(ctx, id, t.Environment, secret)
+			},
+		}, nil
+
+	default:
+		return secretAPI{
+			publicKey: func(ctx context.Context) (*github.PublicKey, *github.Response, error) {
+				return client.Actions.GetOrgPublicKey(ctx, t.Owner)
+			},
+			get: func(ctx context.Context) (*github.Secret, *github.Response, error) {
+				return client.Actions.GetOrgSecret(ctx, t.Owner, t.Name)
+			},
+			put: func(ctx context.Context, secret *github.EncryptedSecret) (*github.Response, error) {
+				if secret.Visibility = t.Visibility; secret.Visibility == "" {
+					secret.Visibility = "private"
+				}
+
+				for _, name := range t.Repositories {
+					repo, res, err := client.Repositories.Get(ctx, t.Owner, name)
+					if err != nil {
+						return res, errors.Wrapf(err, "get repository %s/%s", t.Owner, name)
+					}
+					secret.SelectedRepositoryIDs = append(secret.SelectedRepositoryIDs, repo.GetID())
+				}
+
+				return client.Actions.CreateOrUpdateOrgSecret(ctx, t.Owner, secret)
+			},
+		}, nil
+	}
+}
+
+// syncSecrets pushes the configured values to all targets and prints the
+// result per target. It reports whether any secret was created or updated.
+func syncSecrets(ctx context.Context, client *github.Client, targets []secretTarget) (bool, error) {
+	changed := false
+	state := viper.GetStringMap(configKey("secrets_state"))
+
+	var syncErr error
+	for _, t := range targets {
+		result, err := syncSecret(ctx, client, t, state)
+		if err != nil {
+			syncErr = errors.Wrapf(err, "sync %s", t)
+			break
+		}
+
+		fmt.Printf("%-10s %s\n", result, t)
+		changed = changed || result != secretUnchanged
+	}
+
+	// keep the state of the secrets that were pushed before a failure
+	if changed {
+		if err := setConfig("secrets_state", state); err != nil {
+			return changed, err
+		}
+	}
+
+	return changed, syncErr
+}
+
+func syncSecret(ctx context.Context, client *github.Client, t secretTarget, state map[string]interface{}) (string, error) {
+	if !secretStore().IsSet(t.Key) {
+		return "", errors.Errorf("%s is not configured", t.Key)
+	}
+
+	value, err := secretStore().Get(t.Key)
+	if err != nil {
+		return "", errors.Wrapf(err, "read %s", t.Key)
+	}
+
+	// the access of org secrets is pushed with the value, so changing it
+	// has to push the secret again
+	access := ""
+	if t.Scope == "org" && t.Visibility != "" {
+		access = t.Visibility + ":" + strings.Join(t.Repositories, ",")
+	}
+
+	sum := sha256.Sum256([]byte(value + access))
+	digest := hex.EncodeToString(sum[:])
+
+	api, err := secretEndpoints(ctx, client, t)
+	if err != nil {
+		return "", err
+	}
+
+	result := secretUpdated
+
+	existing, res, err := api.get(ctx)
+	switch {
+	case res != nil && res.StatusCode == http.StatusNotFound:
+		result = secretCreated
+
+	case err != nil:
+		return "", errors.Wrap(err, "get secret")
+
+	default:
+		// github never returns the value itself: the secret is unchanged if
+		// we pushed the same value last time and nobody touched it since
+		if last, ok := state[t.stateKey()].(map[string]interface{}); ok {
+			if last["digest"] == digest && last["updated_at"] == existing.UpdatedAt.UTC().String() {
+				return secretUnchanged, nil
+			}
+		}
+	}
+
+	pub, _, err := api.publicKey(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "get public key")
+	}
+
+	encrypted, err := encrypt(value, pub.GetKey())
+	if err != nil {
+		return "", errors.Wrap(err, "encrypt secret")
+	}
+
+	if _, err := api.put(ctx, &github.EncryptedSecret{
+		Name:           t.Name,
+		KeyID:          pub.GetKeyID(),
+		EncryptedValue: encrypted,
+	}); err != nil {
+		return "", errors.Wrap(err, "update secret")
+	}
+
+	updated, _, err := api.get(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "get updated secret")
+	}
+
+	state[t.stateKey()] = map[string]interface{}{
+		"digest":     digest,
+		"updated_at": updated.UpdatedAt.UTC().String(),
+	}
+
+	return result, nil
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsSyncCmd)
+}
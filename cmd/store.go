@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"gopkg.in/yaml.v3"
+)
+
+// tokenStore is the storage backend for tokens and other secrets. Values are
+// persisted right away when they are set.
+type tokenStore interface {
+	IsSet(key string) bool
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+var store tokenStore
+
+// secretStore returns the backend configured with the 'store' key. It is only
+// opened once per invocation.
+func secretStore() tokenStore {
+	if store != nil {
+		return store
+	}
+
+	switch backend := viper.GetString("store"); backend {
+	case "", "config":
+		store = configStore{}
+	case "vault":
+		store = &vaultStore{}
+	default:
+		cobra.CheckErr(errors.Errorf("unknown store backend %q", backend))
+	}
+
+	return store
+}
+
+// configValue reads a value through the store, so encrypted values are
+// decrypted and references (env:, file:, exec:) are resolved transparently.
+func configValue(key string) string {
+	value, err := secretStore().Get(key)
+	if err != nil {
+		cobra.CheckErr(errors.Wrapf(err, "read %s", key))
+	}
+
+	return value
+}
+
+// persistToken returns a saver that writes refreshed tokens back to the store,
+// so the config always contains the latest one.
+func persistToken(key string) functions.TokenSaver {
+	return func(token *functions.Token) error {
+		encoded, err := token.Encode()
+		if err != nil {
+			return err
+		}
+
+		return secretStore().Set(key, encoded)
+	}
+}
+
+// googleAuth returns the authentication for the google apis. If a service
+// account key is configured ('service_account', optionally impersonating
+// 'service_account_subject'), it is used. Otherwise the user token stored
+// under key is used and refreshed tokens are written back to the store.
+func googleAuth(key string) functions.GoogleAuth {
+	if configIsSet("service_account") {
+		return functions.GoogleAuth{
+			ServiceAccount: configValue("service_account"),
+			Subject:        configValue("service_account_subject"),
+		}
+	}
+
+	if !secretStore().IsSet(key) {
+		cobra.CheckErr(errors.Errorf("no %s configured", key))
+	}
+
+	token, err := secretStore().Get(key)
+	if err != nil {
+		cobra.CheckErr(errors.Wrapf(err, "read %s", key))
+	}
+
+	return functions.GoogleAuth{
+		Credentials: configValue("credentials"),
+		Token:       token,
+		Save:        persistToken(key),
+	}
+}
+
+// configStore keeps the values in plain text in the config file.
+type configStore struct{}
+
+func (configStore) IsSet(key string) bool {
+	return configIsSet(key)
+}
+
+func (configStore) Get(key string) (string, error) {
+	value, err := resolveReference(configString(key))
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve %s", key)
+	}
+
+	return value, nil
+}
+
+func (configStore) Set(key, value string) error {
+	if err := checkWritable(key); err != nil {
+		return err
+	}
+
+	return setConfig(key, value)
+}
+
+func (configStore) Delete(key string) error {
+	return unsetConfig(configKey(key))
+}
+
+// unsetConfig removes a key, which may be nested ('profiles.staging.x'), from
+// the config file. Viper can't unset keys, so the file is written without the
+// key and read again.
+func unsetConfig(key string) error {
+	// mask values that were set during this invocation
+	viper.Set(key, nil)
+
+	settings := viper.AllSettings()
+	path := strings.Split(key, ".")
+	parent := settings
+	for _, name := range path[:len(path)-1] {
+		child, ok := parent[name].(map[string]interface{})
+		if !ok {
+			break
+		}
+		parent = child
+	}
+	delete(parent, path[len(path)-1])
+
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "encode config")
+	}
+
+	filename := viper.ConfigFileUsed()
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return errors.Wrap(err, "stat config file")
+	}
+
+	if err := os.WriteFile(filename, data, info.Mode().Perm()); err != nil {
+		return errors.Wrapf(err, "remove %s from config", key)
+	}
+
+	return viper.ReadInConfig()
+}
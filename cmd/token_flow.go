@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+var (
+	noBrowser    bool
+	callbackPort int
+	redirectURL  string
+	flowTimeout  time.Duration
+	scopeTargets []string
+)
+
+// addFlowFlags adds the flags that control the consent flow to commands that
+// renew tokens.
+func addFlowFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&noBrowser, "no-browser", false, "Print the auth url and read the code from stdin instead of opening a browser")
+	cmd.PersistentFlags().IntVar(&callbackPort, "port", 0, "Port of the local oauth callback server (default the port of the redirect url or 3333)")
+	cmd.PersistentFlags().StringVar(&redirectURL, "redirect-url", "", "Redirect url registered for the oauth client (default the first redirect uri of the credentials)")
+	cmd.PersistentFlags().DurationVar(&flowTimeout, "timeout", 5*time.Minute, "How long to wait for the oauth redirect")
+	cmd.PersistentFlags().StringSliceVar(&scopeTargets, "for", nil, "Only request the scopes these commands need, e.g. 'upload speaker' or 'functions' (default all)")
+}
+
+// selectedTokens returns the names of the selected tokens in the order in
+// which they are renewed.
+func selectedTokens(gmail, sheets, drive bool) []string {
+	names := []string{}
+	if sheets {
+		names = append(names, "sheets")
+	}
+	if gmail {
+		names = append(names, "gmail")
+	}
+	if drive {
+		names = append(names, "drive")
+	}
+
+	return names
+}
+
+// renewToken runs the consent flow for the named token and stores the new
+// token right away, so a failing flow later on doesn't throw away the ones
+// that already succeeded. The requested scopes are the union of what the
+// commands selected with --for need.
+func renewToken(ctx context.Context, name string) error {
+	if !configIsSet("credentials") {
+		return errors.New("no client credentials configured")
+	}
+
+	scopes, err := scopesFor(name, scopeTargets)
+	if err != nil {
+		return err
+	}
+
+	flow, err := newTokenFlow(name, []byte(configValue("credentials")), redirectURL, scopes...)
+	if err != nil {
+		return err
+	}
+
+	var token *oauth2.Token
+	if noBrowser {
+		token, err = flow.runManual(ctx, os.Stdin, os.Stdout)
+	} else {
+		token, err = flow.runBrowser(ctx, callbackPort, flowTimeout)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "renew %s token", name)
+	}
+
+	encoded, err := functions.NewToken(token).Encode()
+	if err != nil {
+		return err
+	}
+
+	if err := secretStore().Set(name+"_token", encoded); err != nil {
+		return errors.Wrapf(err, "update config with new %s token", name)
+	}
+
+	fmt.Printf("updated %s token\n", name)
+
+	return nil
+}
+
+// tokenFlow runs the oauth consent flow for a single token. Every flow has its
+// own config, state, PKCE verifier and callback server, so multiple flows in
+// one invocation don't share anything.
+type tokenFlow struct {
+	name     string
+	config   *oauth2.Config
+	state    string
+	verifier string
+}
+
+// flowResult is handed from the callback handler to the cli.
+type flowResult struct {
+	token *oauth2.Token
+	err   error
+}
+
+func newTokenFlow(name string, credentials []byte, redirectURL string, scopes ...string) (*tokenFlow, error) {
+	config, err := google.ConfigFromJSON(credentials, scopes...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s client secret", name)
+	}
+
+	if redirectURL != "" {
+		config.RedirectURL = redirectURL
+	}
+	if config.RedirectURL == "" {
+		config.RedirectURL = "http://localhost/"
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "generate state")
+	}
+
+	return &tokenFlow{
+		name:     name,
+		config:   config,
+		state:    base64.RawURLEncoding.EncodeToString(b),
+		verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+// authCodeURL returns the url the user has to visit. The random state and the
+// PKCE challenge make sure that codes can neither be injected from another
+// flow nor exchanged by someone who only intercepted the redirect.
+func (f *tokenFlow) authCodeURL() string {
+	return f.config.AuthCodeURL(f.state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(f.verifier))
+}
+
+func (f *tokenFlow) validState(s string) bool {
+	return subtle.ConstantTimeCompare([]byte(s), []byte(f.state)) == 1
+}
+
+func (f *tokenFlow) exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	token, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		return nil, errors.Wrap(err, "get token from code")
+	}
+
+	return token, nil
+}
+
+// runBrowser opens the consent page in the browser and waits for the redirect
+// on a loopback-only callback server listening on port (0 for the port of the
+// redirect url). The server is shut down once the flow has finished, failed or
+// timed out.
+func (f *tokenFlow) runBrowser(ctx context.Context, port int, timeout time.Duration) (*oauth2.Token, error) {
+	redirect, port, err := callbackAddress(f.config.RedirectURL, port)
+	if err != nil {
+		return nil, err
+	}
+	f.config.RedirectURL = redirect.String()
+
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+
+	results := make(chan flowResult, 1)
+	once := &sync.Once{}
+	done := func(res flowResult) {
+		once.Do(func() { results <- res })
+	}
+
+	r := chi.NewRouter()
+	r.Get(path, func(w http.ResponseWriter, r *http.Request) {
+		// requests without the state of this flow were not initiated by us,
+		// so they don't end the flow either
+		if !f.validState(r.URL.Query().Get("state")) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid state"))
+			return
+		}
+
+		if msg := r.URL.Query().Get("error"); msg != "" {
+			done(flowResult{err: errors.New("authorization failed: " + msg)})
+			_, _ = w.Write([]byte("authorization failed: " + msg))
+			return
+		}
+
+		token, err := f.exchange(r.Context(), r.URL.Query().Get("code"))
+		if err != nil {
+			done(flowResult{err: err})
+			_, _ = w.Write([]byte("failed to update " + f.name + " token: " + err.Error()))
+			return
+		}
+
+		done(flowResult{token: token})
+		_, _ = w.Write([]byte("updated " + f.name + " token, you can close this window"))
+	})
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, errors.Wrap(err, "listen for oauth callback")
+	}
+
+	server := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			done(flowResult{err: errors.Wrap(err, "serve oauth callback")})
+		}
+	}()
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	if err := exec.Command("xdg-open", f.authCodeURL()).Start(); err != nil {
+		return nil, errors.Wrapf(err, "open %s auth url", f.name)
+	}
+
+	select {
+	case res := <-results:
+		return res.token, res.err
+	case <-time.After(timeout):
+		return nil, errors.Errorf("no redirect for the %s token within %s, use --no-browser on machines without a browser", f.name, timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// callbackAddress returns the redirect url and the port the callback server
+// listens on. Loopback redirect urls without a port match any port, so the
+// port is added to them (default 3333).
+func callbackAddress(redirectURL string, port int) (*url.URL, int, error) {
+	redirect, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "parse redirect url")
+	}
+
+	if redirect.Port() == "" {
+		if port == 0 {
+			port = 3333
+		}
+		redirect.Host = net.JoinHostPort(redirect.Hostname(), strconv.Itoa(port))
+
+		return redirect, port, nil
+	}
+
+	p, err := strconv.Atoi(redirect.Port())
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "parse port of redirect url")
+	}
+
+	if port != 0 && port != p {
+		return nil, 0, errors.Errorf("--port %d doesn't match the redirect url %s, set --redirect-url too", port, redirectURL)
+	}
+
+	return redirect, p, nil
+}
+
+// runManual runs the consent flow without a local browser. The auth url is
+// printed to out and the user pastes either the code or the full redirect url
+// (which fails to load on a headless machine) back to in.
+func (f *tokenFlow) runManual(ctx context.Context, in io.Reader, out io.Writer) (*oauth2.Token, error) {
+	fmt.Fprintf(out, "=> open the following url in a browser and grant access to %s:\n", f.name)
+	fmt.Fprintln(out, f.authCodeURL())
+	fmt.Fprint(out, "=> paste the code or the redirect url: ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return nil, errors.Wrap(err, "read code from stdin")
+	}
+
+	code, state, err := parseAuthCode(line)
+	if err != nil {
+		return nil, err
+	}
+
+	// a bare code carries no state, only a pasted redirect url can be checked
+	if state != "" && !f.validState(state) {
+		return nil, errors.New("state in redirect url does not match")
+	}
+
+	return f.exchange(ctx, code)
+}
+
+// parseAuthCode extracts the auth code from the pasted input, which is either
+// the bare code or the redirect url containing it as query parameter. The
+// state is only returned for redirect urls.
+func parseAuthCode(input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", "", errors.New("no code provided")
+	}
+
+	if !strings.Contains(input, "://") {
+		return input, "", nil
+	}
+
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", "", errors.Wrap(err, "parse redirect url")
+	}
+
+	if msg := u.Query().Get("error"); msg != "" {
+		return "", "", errors.New("authorization failed: " + msg)
+	}
+
+	code := u.Query().Get("code")
+	if code == "" {
+		return "", "", errors.New("redirect url does not contain a code")
+	}
+
+	return code, u.Query().Get("state"), nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParseAuthCode(t *testing.T) {
+	tests := []struct {
+		in    string
+		code  string
+		state string
+		err   bool
+	}{
+		{in: " 4/abc \n", code: "4/abc"},
+		{in: "http://localhost:3333/?state=xyz&code=4/abc&scope=s", code: "4/abc", state: "xyz"},
+		{in: "http://localhost:3333/?error=access_denied&state=xyz", err: true},
+		{in: "http://localhost:3333/?state=xyz", err: true},
+		{in: "  ", err: true},
+	}
+
+	for _, tt := range tests {
+		code, state, err := parseAuthCode(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseAuthCode(%q) error = %v", tt.in, err)
+			continue
+		}
+		if code != tt.code || state != tt.state {
+			t.Errorf("parseAuthCode(%q) = %q, %q, want %q, %q", tt.in, code, state, tt.code, tt.state)
+		}
+	}
+}
+
+func TestCallbackAddress(t *testing.T) {
+	tests := []struct {
+		redirect string
+		port     int
+		want     string
+		wantPort int
+		err      bool
+	}{
+		{redirect: "http://localhost:3333", want: "http://localhost:3333", wantPort: 3333},
+		{redirect: "http://localhost:8080/cb", want: "http://localhost:8080/cb", wantPort: 8080},
+		{redirect: "http://localhost/", want: "http://localhost:3333/", wantPort: 3333},
+		{redirect: "http://localhost/", port: 9000, want: "http://localhost:9000/", wantPort: 9000},
+		{redirect: "http://localhost:8080/cb", port: 9000, err: true},
+	}
+
+	for _, tt := range tests {
+		u, port, err := callbackAddress(tt.redirect, tt.port)
+		if (err != nil) != tt.err {
+			t.Errorf("callbackAddress(%q, %d) error = %v", tt.redirect, tt.port, err)
+			continue
+		}
+		if err == nil && (u.String() != tt.want || port != tt.wantPort) {
+			t.Errorf("callbackAddress(%q, %d) = %s, %d, want %s, %d", tt.redirect, tt.port, u, port, tt.want, tt.wantPort)
+		}
+	}
+}
@@ -1,41 +1,220 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"golang.org/x/oauth2/google"
+	"gopkg.in/yaml.v3"
 )
 
-var tokenPrintCmd = &cobra.Command{
-	Use:   "print",
-	Short: "Print the currently configured tokens",
-	Run: func(cmd *cobra.Command, args []string) {
-		if viper.IsSet("credentials") {
-			fmt.Println("=> credentials:")
-			fmt.Println(viper.GetString("credentials"))
-		} else {
-			fmt.Println("no credentials configured")
+var (
+	revealSecrets bool
+	printOutput   string
+	printKey      string
+
+	printableKeys = []string{"credentials", "gmail_token", "sheets_token", "drive_token"}
+
+	tokenPrintCmd = &cobra.Command{
+		Use:   "print",
+		Short: "Print the currently configured tokens",
+		Long: `Prints a redacted view of the client credentials and the tokens: client id,
+token type, expiry, whether a refresh token is present and a fingerprint of
+the secret value. Raw values are only printed with --reveal, for example:
+
+snctl token print --key gmail_token --reveal`,
+		Run: func(cmd *cobra.Command, args []string) {
+			keys := printableKeys
+			if printKey != "" {
+				if !contains(printableKeys, printKey) {
+					cobra.CheckErr(errors.Errorf("unknown key %q, expected one of %s", printKey, strings.Join(printableKeys, ", ")))
+				}
+
+				keys = []string{printKey}
+			}
+
+			entries := []printEntry{}
+			for _, key := range keys {
+				entry, err := newPrintEntry(key)
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				entries = append(entries, entry)
+			}
+
+			var out interface{} = entries
+			if printKey != "" {
+				out = entries[0]
+			}
+
+			switch printOutput {
+			case "json":
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(out); err != nil {
+					cobra.CheckErr(errors.Wrap(err, "encode json"))
+				}
+
+			case "yaml":
+				enc := yaml.NewEncoder(os.Stdout)
+				enc.SetIndent(2)
+				if err := enc.Encode(out); err != nil {
+					cobra.CheckErr(errors.Wrap(err, "encode yaml"))
+				}
+
+			case "text":
+				// a single revealed value is printed as is, so it can be piped
+				if printKey != "" && revealSecrets {
+					if !entries[0].Configured {
+						cobra.CheckErr(errors.Errorf("%s is not configured", printKey))
+					}
+
+					fmt.Println(entries[0].Value)
+					return
+				}
+
+				for _, entry := range entries {
+					entry.print()
+				}
+
+				if printKey == "" && configIsSet("secrets_url") {
+					fmt.Println("=> token can be updated here: " + configValue("secrets_url"))
+				}
+
+			default:
+				cobra.CheckErr(errors.Errorf("unsupported output format %q", printOutput))
+			}
+		},
+	}
+)
+
+type printEntry struct {
+	Key          string     `json:"key" yaml:"key"`
+	Configured   bool       `json:"configured" yaml:"configured"`
+	ClientID     string     `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	TokenType    string     `json:"token_type,omitempty" yaml:"token_type,omitempty"`
+	Expiry       *time.Time `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+	RefreshToken *bool      `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	Fingerprint  string     `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Value        string     `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+func newPrintEntry(key string) (printEntry, error) {
+	entry := printEntry{Key: key}
+
+	var value string
+	if key == "credentials" {
+		if !configIsSet(key) {
+			return entry, nil
 		}
 
-		if viper.IsSet("gmail_token") {
-			fmt.Println("=> gmail token:")
-			fmt.Println(viper.GetString("gmail_token"))
-		} else {
-			fmt.Println("no gmail token configured")
+		value = configValue(key)
+
+		config, err := google.ConfigFromJSON([]byte(value))
+		if err != nil {
+			return entry, errors.Wrap(err, "parse client credentials")
+		}
+
+		entry.ClientID = config.ClientID
+		entry.Fingerprint = fingerprint(config.ClientSecret)
+	} else {
+		if !secretStore().IsSet(key) {
+			return entry, nil
 		}
 
-		if viper.IsSet("sheets_token") {
-			fmt.Println("=> sheets token:")
-			fmt.Println(viper.GetString("sheets_token"))
+		var err error
+		value, err = secretStore().Get(key)
+		if err != nil {
+			return entry, errors.Wrapf(err, "read %s", key)
+		}
+
+		token, err := functions.ParseToken(value)
+		if err != nil {
+			return entry, errors.Wrapf(err, "parse %s", key)
+		}
+
+		hasRefresh := token.RefreshToken != ""
+		entry.TokenType = token.Type()
+		entry.RefreshToken = &hasRefresh
+
+		if !token.Expiry.IsZero() {
+			entry.Expiry = &token.Expiry
+		}
+
+		if hasRefresh {
+			entry.Fingerprint = fingerprint(token.RefreshToken)
 		} else {
-			fmt.Println("no sheets token configured")
+			entry.Fingerprint = fingerprint(token.AccessToken)
+		}
+	}
+
+	entry.Configured = true
+	if revealSecrets {
+		entry.Value = strings.TrimSpace(value)
+	}
+
+	return entry, nil
+}
+
+func (e printEntry) print() {
+	fmt.Printf("=> %s:\n", strings.ReplaceAll(e.Key, "_", " "))
+
+	if !e.Configured {
+		fmt.Println("   not configured")
+		return
+	}
+
+	if e.ClientID != "" {
+		fmt.Printf("   client id:     %s\n", e.ClientID)
+	}
+
+	if e.TokenType != "" {
+		fmt.Printf("   type:          %s\n", e.TokenType)
+	}
+
+	if e.Expiry != nil {
+		fmt.Printf("   expiry:        %s\n", e.Expiry.Local().Format(time.RFC3339))
+	}
+
+	if e.RefreshToken != nil {
+		fmt.Printf("   refresh token: %s\n", yesNo(*e.RefreshToken))
+	}
+
+	fmt.Printf("   fingerprint:   %s\n", e.Fingerprint)
+
+	if e.Value != "" {
+		fmt.Printf("   value:         %s\n", e.Value)
+	}
+}
+
+// fingerprint identifies a secret without revealing it.
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
+	}
 
-		fmt.Println("=> token can be updated here: " + viper.GetString("secrets_url"))
-	},
+	return false
 }
 
 func init() {
 	tokenCmd.AddCommand(tokenPrintCmd)
+	tokenPrintCmd.Flags().BoolVar(&revealSecrets, "reveal", false, "Print the raw values of credentials and tokens")
+	tokenPrintCmd.Flags().StringVarP(&printOutput, "output", "o", "text", "Output format: text, json or yaml")
+	tokenPrintCmd.Flags().StringVar(&printKey, "key", "", "Only print the given key ("+strings.Join(printableKeys, ", ")+")")
 }
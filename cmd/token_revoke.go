@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var (
+	revokeDriveToken  bool
+	revokeGmailToken  bool
+	revokeSheetsToken bool
+	revokeURL         string
+
+	tokenRevokeCmd = &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke tokens at google and remove them from the config file",
+		Run: func(cmd *cobra.Command, args []string) {
+			names := selectedTokens(revokeGmailToken, revokeSheetsToken, revokeDriveToken)
+			if len(names) == 0 {
+				cobra.CheckErr(errors.New("select at least one of --gmail, --sheets or --drive"))
+			}
+
+			for _, name := range names {
+				if err := revokeStoredToken(cmd.Context(), name); err != nil {
+					cobra.CheckErr(err)
+				}
+			}
+		},
+	}
+)
+
+// revokeStoredToken revokes the stored token and removes it from the store.
+func revokeStoredToken(ctx context.Context, name string) error {
+	key := name + "_token"
+	if !secretStore().IsSet(key) {
+		fmt.Printf("no %s token configured\n", name)
+		return nil
+	}
+
+	data, err := secretStore().Get(key)
+	if err != nil {
+		return errors.Wrapf(err, "read %s token", name)
+	}
+
+	token, err := functions.ParseToken(data)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s token", name)
+	}
+
+	if err := functions.RevokeToken(ctx, revokeURL, token); err != nil {
+		return errors.Wrapf(err, "revoke %s token", name)
+	}
+
+	if err := secretStore().Delete(key); err != nil {
+		return errors.Wrapf(err, "remove %s token from config", name)
+	}
+
+	fmt.Printf("revoked %s token\n", name)
+
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	tokenRevokeCmd.Flags().BoolVar(&revokeGmailToken, "gmail", false, "Revoke the gmail token")
+	tokenRevokeCmd.Flags().BoolVar(&revokeSheetsToken, "sheets", false, "Revoke the sheets token")
+	tokenRevokeCmd.Flags().BoolVar(&revokeDriveToken, "drive", false, "Revoke the drive token")
+	tokenRevokeCmd.Flags().StringVar(&revokeURL, "revoke-url", functions.DefaultRevokeURL, "OAuth revocation endpoint")
+}
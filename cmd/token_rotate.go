@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var (
+	rotateDriveToken  bool
+	rotateGmailToken  bool
+	rotateSheetsToken bool
+
+	tokenRotateCmd = &cobra.Command{
+		Use:   "rotate",
+		Short: "Revoke, renew and push tokens to the github secrets in one step",
+		Long: `Revokes the selected tokens, runs the consent flow for new ones and pushes
+them to every configured secret target that is fed by them. Every rotation is
+recorded in the audit log (next to the config file or at 'audit_log').
+
+The old token is revoked before the consent flow, as google revokes the whole
+grant of the client. If the flow fails, run 'token update' for the token.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			names := selectedTokens(rotateGmailToken, rotateSheetsToken, rotateDriveToken)
+			if len(names) == 0 {
+				cobra.CheckErr(errors.New("select at least one of --gmail, --sheets or --drive"))
+			}
+
+			entry := auditEntry{Action: "token rotate", Tokens: names}
+			err := rotateTokens(cmd.Context(), names, &entry)
+
+			if aerr := writeAudit(entry, err); aerr != nil {
+				fmt.Printf("failed to write audit log: %v\n", aerr)
+			}
+
+			if err != nil {
+				cobra.CheckErr(err)
+			}
+		},
+	}
+)
+
+// rotateTokens revokes and renews the tokens and pushes them to the secret
+// targets. The old token has to be revoked before the consent flow: google
+// revokes the whole grant of the client, so revoking it afterwards would
+// invalidate the new token as well.
+func rotateTokens(ctx context.Context, names []string, entry *auditEntry) error {
+	keys := map[string]bool{}
+
+	for _, name := range names {
+		revoked := secretStore().IsSet(name + "_token")
+		if err := revokeStoredToken(ctx, name); err != nil {
+			return err
+		}
+
+		if err := renewToken(ctx, name); err != nil {
+			if revoked {
+				return errors.Wrapf(err, "the %s token was revoked but no new one was stored, run 'snctl token update --%s'", name, name)
+			}
+			return err
+		}
+
+		keys[name+"_token"] = true
+	}
+
+	targets := []secretTarget{}
+	for _, t := range secretTargets() {
+		if keys[t.Key] {
+			targets = append(targets, t)
+			entry.Secrets = append(entry.Secrets, t.String())
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("=> no secret targets configured for the rotated tokens")
+		return nil
+	}
+
+	if _, err := syncSecrets(ctx, newGithubClient(), targets); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRotateCmd)
+	tokenRotateCmd.Flags().BoolVar(&rotateGmailToken, "gmail", false, "Rotate the gmail token")
+	tokenRotateCmd.Flags().BoolVar(&rotateSheetsToken, "sheets", false, "Rotate the sheets token")
+	tokenRotateCmd.Flags().BoolVar(&rotateDriveToken, "drive", false, "Rotate the drive token")
+	tokenRotateCmd.Flags().StringVar(&revokeURL, "revoke-url", functions.DefaultRevokeURL, "OAuth revocation endpoint")
+	addFlowFlags(tokenRotateCmd)
+}
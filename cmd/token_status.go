@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+var (
+	validateTokens bool
+	tokenURL       string
+	statusOutput   string
+
+	tokenStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show expiry, scopes and validity of the configured tokens",
+		Long: `Decodes the configured tokens and shows their expiry, whether a refresh token
+is present and which scopes were granted. With --validate every token is
+refreshed against the token endpoint of the client credentials (or
+--token-url). Exits non-zero if a configured token is invalid.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if statusOutput != "text" && statusOutput != "json" {
+				cobra.CheckErr(errors.Errorf("unsupported output format %q", statusOutput))
+			}
+
+			var config *oauth2.Config
+			if validateTokens {
+				var err error
+				if config, err = refreshConfig(); err != nil {
+					cobra.CheckErr(err)
+				}
+			}
+
+			statuses, err := checkTokens(cmd.Context(), config)
+
+			if statusOutput == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(statuses); err != nil {
+					cobra.CheckErr(errors.Wrap(err, "encode token status"))
+				}
+			} else {
+				for _, status := range statuses {
+					status.print()
+				}
+			}
+
+			if err != nil {
+				cobra.CheckErr(err)
+			}
+		},
+	}
+)
+
+type tokenStatus struct {
+	Key          string     `json:"key"`
+	Configured   bool       `json:"configured"`
+	Valid        bool       `json:"valid"`
+	Expiry       *time.Time `json:"expiry,omitempty"`
+	Expired      bool       `json:"expired"`
+	RefreshToken bool       `json:"refresh_token"`
+	Scopes       []string   `json:"scopes"`
+	Refreshed    *bool      `json:"refreshed,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// refreshConfig returns the config of the client credentials used to refresh
+// the tokens, with the token endpoint of --token-url if set.
+func refreshConfig() (*oauth2.Config, error) {
+	if !configIsSet("credentials") {
+		return nil, errors.New("no client credentials configured")
+	}
+
+	config, err := google.ConfigFromJSON([]byte(configValue("credentials")))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse client credentials")
+	}
+
+	if tokenURL != "" {
+		config.Endpoint.TokenURL = tokenURL
+	}
+
+	return config, nil
+}
+
+// checkTokens checks every token. The statuses are returned along with an
+// error if a configured token is invalid.
+func checkTokens(ctx context.Context, config *oauth2.Config) ([]tokenStatus, error) {
+	statuses := []tokenStatus{}
+	invalid := 0
+
+	for _, key := range []string{"gmail_token", "sheets_token", "drive_token"} {
+		status := checkToken(ctx, key, config)
+		if status.Configured && !status.Valid {
+			invalid++
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	if invalid > 0 {
+		return statuses, errors.Errorf("%d invalid token(s)", invalid)
+	}
+
+	return statuses, nil
+}
+
+// checkToken decodes the token stored under key. If config is set, the token
+// is refreshed to make sure that it is still accepted by google.
+func checkToken(ctx context.Context, key string, config *oauth2.Config) tokenStatus {
+	status := tokenStatus{Key: key, Scopes: []string{}}
+
+	if !secretStore().IsSet(key) {
+		return status
+	}
+	status.Configured = true
+
+	data, err := secretStore().Get(key)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	token, err := functions.ParseToken(data)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	if !token.Expiry.IsZero() {
+		status.Expiry = &token.Expiry
+		status.Expired = token.Expiry.Before(time.Now())
+	}
+	status.RefreshToken = token.RefreshToken != ""
+	status.Scopes = append(status.Scopes, token.Scopes()...)
+	status.Valid = !status.Expired || status.RefreshToken
+
+	if config == nil || !status.RefreshToken {
+		return status
+	}
+
+	// an empty access token forces the token source to refresh
+	refreshed, err := config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	ok := err == nil
+	status.Refreshed = &ok
+
+	if err != nil {
+		status.Valid = false
+		status.Error = errors.Wrap(err, "refresh token").Error()
+		return status
+	}
+
+	if len(status.Scopes) == 0 {
+		status.Scopes = append(status.Scopes, functions.NewToken(refreshed).Scopes()...)
+	}
+
+	return status
+}
+
+func (s tokenStatus) print() {
+	fmt.Printf("=> %s:\n", strings.ReplaceAll(s.Key, "_", " "))
+
+	if !s.Configured {
+		fmt.Println("   not configured")
+		return
+	}
+
+	if s.Expiry != nil {
+		expiry := s.Expiry.Local().Format(time.RFC3339)
+		if s.Expired {
+			expiry += " (expired)"
+		}
+		fmt.Printf("   expiry:        %s\n", expiry)
+	}
+
+	fmt.Printf("   refresh token: %s\n", yesNo(s.RefreshToken))
+
+	if len(s.Scopes) == 0 {
+		fmt.Println("   scopes:        unknown")
+	} else {
+		fmt.Printf("   scopes:        %s\n", strings.Join(s.Scopes, "\n                  "))
+	}
+
+	if s.Refreshed != nil {
+		result := "ok"
+		if !*s.Refreshed {
+			result = "failed"
+		}
+		fmt.Printf("   refresh:       %s\n", result)
+	}
+
+	if s.Error != "" {
+		fmt.Printf("   error:         %s\n", s.Error)
+	}
+
+	fmt.Printf("   valid:         %s\n", yesNo(s.Valid))
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenStatusCmd)
+	tokenStatusCmd.Flags().BoolVar(&validateTokens, "validate", false, "Refresh every token to check that it is still accepted")
+	tokenStatusCmd.Flags().StringVar(&tokenURL, "token-url", "", "Token endpoint used for --validate (default from the client credentials)")
+	tokenStatusCmd.Flags().StringVarP(&statusOutput, "output", "o", "text", "Output format: text or json")
+}
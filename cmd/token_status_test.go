@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const testCredentials = `{"installed":{"client_id":"id","client_secret":"secret","redirect_uris":["http://localhost"],"token_uri":"https://oauth2.googleapis.com/token"}}`
+
+// tokenStub stands in for the google token endpoint. Refresh tokens starting
+// with "valid" are refreshed, all others are rejected with invalid_grant.
+func tokenStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.PostFormValue("grant_type") != "refresh_token" || !strings.HasPrefix(r.PostFormValue("refresh_token"), "valid") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"access_token":"new","token_type":"Bearer","expires_in":3599,"scope":"https://www.googleapis.com/auth/drive"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCheckTokens(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		tokens  map[string]string
+		valid   map[string]bool
+		wantErr bool
+	}{
+		{
+			name: "refreshed",
+			tokens: map[string]string{
+				"drive_token": fmt.Sprintf(`{"access_token":"old","refresh_token":"valid","expiry":%q}`, expired),
+			},
+			valid: map[string]bool{"drive_token": true},
+		},
+		{
+			name: "invalid grant",
+			tokens: map[string]string{
+				"drive_token":  `{"access_token":"old","refresh_token":"valid"}`,
+				"sheets_token": `{"access_token":"old","refresh_token":"revoked"}`,
+			},
+			valid:   map[string]bool{"drive_token": true, "sheets_token": false},
+			wantErr: true,
+		},
+		{
+			name: "expired without refresh token",
+			tokens: map[string]string{
+				"gmail_token": fmt.Sprintf(`{"access_token":"old","expiry":%q}`, expired),
+			},
+			valid:   map[string]bool{"gmail_token": false},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			tokens:  map[string]string{"gmail_token": ""},
+			valid:   map[string]bool{"gmail_token": false},
+			wantErr: true,
+		},
+		{
+			// tokens that aren't configured are reported but don't fail
+			name:  "not configured",
+			valid: map[string]bool{},
+		},
+	}
+
+	server := tokenStub(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("credentials", testCredentials)
+			for key, value := range tt.tokens {
+				viper.Set(key, value)
+			}
+
+			tokenURL = server.URL
+			t.Cleanup(func() { tokenURL = "" })
+
+			config, err := refreshConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			statuses, err := checkTokens(context.Background(), config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkTokens() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			for _, status := range statuses {
+				valid, configured := tt.valid[status.Key]
+				if status.Configured != configured {
+					t.Errorf("%s configured = %v, want %v", status.Key, status.Configured, configured)
+				}
+				if status.Valid != valid {
+					t.Errorf("%s valid = %v, want %v (%s)", status.Key, status.Valid, valid, status.Error)
+				}
+			}
+		})
+	}
+}
+
+// The scopes of a token written before they were stored are taken from the
+// refresh.
+func TestCheckTokenScopes(t *testing.T) {
+	server := tokenStub(t)
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("credentials", testCredentials)
+	viper.Set("drive_token", `{"access_token":"old","refresh_token":"valid"}`)
+
+	tokenURL = server.URL
+	t.Cleanup(func() { tokenURL = "" })
+
+	config, err := refreshConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := checkToken(context.Background(), "drive_token", config)
+	if status.Refreshed == nil || !*status.Refreshed {
+		t.Fatalf("token wasn't refreshed: %s", status.Error)
+	}
+	if len(status.Scopes) != 1 || status.Scopes[0] != "https://www.googleapis.com/auth/drive" {
+		t.Errorf("scopes = %v, want the ones of the refresh", status.Scopes)
+	}
+}
@@ -1,28 +1,15 @@
 package cmd
 
 import (
-	"bytes"
-	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
-	"net/http"
-	"os/exec"
-	"strings"
-	"sync"
+	"fmt"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/google/go-github/v56/github"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/nacl/box"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/drive/v2"
-	"google.golang.org/api/gmail/v1"
 )
 
 var (
@@ -30,190 +17,41 @@ var (
 	renewGmailToken          bool
 	renewSheetsToken         bool
 	updateEnvironmentSecrets bool
-	config                   *oauth2.Config
+	followRuns               bool
+	pollInterval             time.Duration
+	followTimeout            time.Duration
 
 	tokenUpdateCmd = &cobra.Command{
 		Use:   "update",
 		Short: "Renew the tokens and update them in the config file",
+		Long: `Runs the oauth consent flow for every selected token. By default the consent
+page is opened in the browser and the redirect is received on a local
+callback server (127.0.0.1 only). The redirect url defaults to
+http://localhost:<port>/ and has to be allowed for the oauth client.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if renewGmailToken || renewSheetsToken || renewDriveToken {
-				if !viper.IsSet("credentials") {
-					cobra.CheckErr(errors.New("no sheets token file configured"))
-				}
-
-				// set up a waitgroup to make sure that - in case we update both
-				// tokens - we don't overwrite the global oauth object. Earlier
-				// this was not necessary because it required the input from the
-				// user
-				wg := &sync.WaitGroup{}
-
-				// setup a server to extract the auth token from the url
-				// the url itself can be adjusted in the 'credentials' - there is a
-				// field "redirect" or something like that
-				r := chi.NewRouter()
-				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-					defer wg.Done()
-
-					scope := r.URL.Query().Get("scope")
-					code := r.URL.Query().Get("code")
-
-					token, err := config.Exchange(context.TODO(), code)
-					if err != nil {
-						_, _ = w.Write([]byte("failed to get token from code: " + err.Error()))
-					}
-
-					var buf bytes.Buffer
-					if err := json.NewEncoder(&buf).Encode(token); err != nil {
-						_, _ = w.Write([]byte("failed to encode token to json: " + err.Error()))
-					}
-
-					if strings.Contains(scope, "drive") {
-						scope = "drive"
-					}
-
-					switch scope {
-					case "https://www.googleapis.com/auth/gmail.compose":
-						viper.Set("gmail_token", buf.String())
-						_, _ = w.Write([]byte("updated gmail token"))
-
-					case "https://www.googleapis.com/auth/spreadsheets":
-						viper.Set("sheets_token", buf.String())
-						_, _ = w.Write([]byte("updated sheets token"))
-
-					case "drive":
-						viper.Set("drive_token", buf.String())
-						_, _ = w.Write([]byte("updated drive token"))
-
-					default:
-						_, _ = w.Write([]byte("unknown scope: " + scope))
-					}
-				})
-
-				go func() {
-					_ = http.ListenAndServe(":3333", r)
-				}()
-
-				b := []byte(viper.GetString("credentials"))
-
-				if renewSheetsToken {
-					var err error
-					wg.Add(1)
-					config, err = google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets")
-					if err != nil {
-						cobra.CheckErr(errors.Wrap(err, "parse sheets client secret"))
-					}
-
-					if err := exec.Command("xdg-open", config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)).Start(); err != nil {
-						cobra.CheckErr(errors.Wrap(err, "open sheets auth url"))
-					}
-
-					wg.Wait()
-				}
-
-				if renewGmailToken {
-					var err error
-					wg.Add(1)
-					config, err = google.ConfigFromJSON(b, gmail.GmailComposeScope)
-					if err != nil {
-						cobra.CheckErr(errors.Wrap(err, "parse gmail client secret"))
-					}
-
-					if err := exec.Command("xdg-open", config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)).Start(); err != nil {
-						cobra.CheckErr(errors.Wrap(err, "open gmail auth url"))
-					}
-
-					wg.Wait()
-				}
-
-				if renewDriveToken {
-					var err error
-					wg.Add(1)
-					config, err = google.ConfigFromJSON(b,
-						drive.DriveScope,
-						drive.DriveFileScope,
-						drive.DriveMetadataScope,
-						drive.DrivePhotosReadonlyScope,
-					)
-					if err != nil {
-						cobra.CheckErr(errors.Wrap(err, "parse drive client secret"))
-					}
-
-					if err := exec.Command("xdg-open", config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)).Start(); err != nil {
-						cobra.CheckErr(errors.Wrap(err, "open drive auth url"))
-					}
-
-					wg.Wait()
-				}
-
-				if err := viper.WriteConfig(); err != nil {
-					cobra.CheckErr(errors.Wrap(err, "update config with new tokens"))
+			for _, name := range selectedTokens(renewGmailToken, renewSheetsToken, renewDriveToken) {
+				if err := renewToken(cmd.Context(), name); err != nil {
+					cobra.CheckErr(err)
 				}
 			}
 
 			if updateEnvironmentSecrets {
-				if !viper.IsSet("github_token") {
-					cobra.CheckErr(errors.New("no github token configured"))
-				}
-
-				// the repo id can be found in the page source of the repository:
-				// <meta content="your-repo-id" name="octolytics-dimension-repository_id" />
-				ctx := context.TODO()
-				client := github.NewClient(nil).WithAuthToken(viper.GetString("github_token"))
+				ctx := cmd.Context()
+				client := newGithubClient()
 
-				pub, _, err := client.Actions.GetEnvPublicKey(ctx, 646451604, "prod")
+				changed, err := syncSecrets(ctx, client, secretTargets())
 				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "get environment public key"))
+					cobra.CheckErr(err)
 				}
 
-				if !viper.IsSet("gmail_token") || !viper.IsSet("sheets_token") {
-					cobra.CheckErr(errors.New("gmail oder sheets token is missing in config"))
+				if !changed {
+					fmt.Println("=> secrets unchanged, skipping workflow dispatch")
+					return
 				}
 
-				secret, err := encrypt(viper.GetString("gmail_token"), pub.GetKey())
-				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "encrypt gmail secret"))
-				}
-				_, err = client.Actions.CreateOrUpdateEnvSecret(ctx, 646451604, "prod", &github.EncryptedSecret{
-					Name:           "GMAIL",
-					KeyID:          *pub.KeyID,
-					EncryptedValue: secret,
-				})
-				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "update gmail token in 'prod' env"))
+				if err := runWorkflows(ctx, client, secretWorkflows(), followRuns, pollInterval, followTimeout); err != nil {
+					cobra.CheckErr(err)
 				}
-
-				secret, err = encrypt(viper.GetString("sheets_token"), pub.GetKey())
-				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "encrypt sheets secret"))
-				}
-				_, err = client.Actions.CreateOrUpdateEnvSecret(ctx, 646451604, "prod", &github.EncryptedSecret{
-					Name:           "SHEETS",
-					KeyID:          *pub.KeyID,
-					EncryptedValue: secret,
-				})
-				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "update sheets token in 'prod' env"))
-				}
-
-				_, err = client.Actions.CreateWorkflowDispatchEventByFileName(ctx,
-					"startup-nights",
-					"functions",
-					"deploy.yml",
-					github.CreateWorkflowDispatchEventRequest{
-						Ref: "main",
-					},
-				)
-				if err != nil {
-					cobra.CheckErr(errors.Wrap(err, "trigger workflow run"))
-				}
-
-				time.Sleep(5 * time.Second)
-
-				if err := exec.Command("xdg-open", "https://github.com/Startup-Nights/functions/actions").Start(); err != nil {
-					cobra.CheckErr(errors.Wrap(err, "open gmail auth url"))
-				}
-
-				time.Sleep(5 * time.Second)
 			}
 		},
 	}
@@ -252,5 +90,9 @@ func init() {
 	tokenUpdateCmd.PersistentFlags().BoolVar(&renewGmailToken, "gmail", false, "Update the gmail token")
 	tokenUpdateCmd.PersistentFlags().BoolVar(&renewSheetsToken, "sheets", false, "Update the sheets token")
 	tokenUpdateCmd.PersistentFlags().BoolVar(&renewDriveToken, "drive", false, "Update the drive token")
-	tokenUpdateCmd.PersistentFlags().BoolVar(&updateEnvironmentSecrets, "update-secrets", false, "Update the github actions environment secrets")
+	tokenUpdateCmd.PersistentFlags().BoolVar(&updateEnvironmentSecrets, "update-secrets", false, "Sync the configured github actions secrets and dispatch the workflows")
+	tokenUpdateCmd.PersistentFlags().BoolVar(&followRuns, "follow", true, "Follow the dispatched workflow runs until they are completed")
+	tokenUpdateCmd.PersistentFlags().DurationVar(&pollInterval, "poll-interval", 5*time.Second, "How often to poll the status of workflow runs")
+	tokenUpdateCmd.PersistentFlags().DurationVar(&followTimeout, "follow-timeout", 30*time.Minute, "How long to wait for a dispatched workflow run to be completed")
+	addFlowFlags(tokenUpdateCmd)
 }
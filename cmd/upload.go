@@ -5,7 +5,6 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/startup-nights/snctl/pkg/functions"
 )
 
@@ -43,10 +42,10 @@ go run main.go upload --dir ~/Downloads --folder 2024/team --type team --height
 			}
 
 			config := functions.SpacesConfig{
-				Bucket: viper.GetString("spaces_bucket"),
-				Region: viper.GetString("spaces_region"),
-				Secret: viper.GetString("spaces_secret"),
-				Key:    viper.GetString("spaces_key"),
+				Bucket: configValue("spaces_bucket"),
+				Region: configValue("spaces_region"),
+				Secret: configValue("spaces_secret"),
+				Key:    configValue("spaces_key"),
 			}
 
 			if err := functions.Upload(config, targetDir, spacesFolder); err != nil {
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var diffFile string
+
+// addDiffFlag adds the flag to compare the entries with the website data
+// file.
+func addDiffFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&diffFile, "diff", "", "Only compare the entries with this website data file (yaml) and exit with an error if they differ, no image is uploaded")
+	cmd.MarkFlagsMutuallyExclusive("diff", "write-to")
+	cmd.MarkFlagsMutuallyExclusive("diff", "validate")
+}
+
+// diffEntries prints the people that would be added, removed or changed in
+// the website data file. It returns an error if there are any changes.
+func diffEntries(entries []entry, root string) error {
+	path, err := listPath(root)
+	if err != nil {
+		return err
+	}
+
+	doc, err := readDataFile(diffFile)
+	if err != nil {
+		return err
+	}
+
+	changes, err := functions.MergeEntries(doc, path, entryValues(entries), true)
+	if err != nil {
+		return err
+	}
+
+	count := map[string]int{}
+	for _, c := range changes {
+		count[c.Kind]++
+
+		switch c.Kind {
+		case functions.EntryAdded:
+			fmt.Printf("+ %s\n", c.Name)
+		case functions.EntryRemoved:
+			fmt.Printf("- %s\n", c.Name)
+		case functions.EntryChanged:
+			fmt.Printf("~ %s\n", c.Name)
+			for _, f := range c.Fields {
+				fmt.Printf("    %s: %q -> %q\n", f.Field, f.Old, f.New)
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "=> %d added, %d removed, %d changed\n",
+		count[functions.EntryAdded], count[functions.EntryRemoved], count[functions.EntryChanged])
+
+	if len(changes) > 0 {
+		return errors.Errorf("%s differs from the source", diffFile)
+	}
+
+	return nil
+}
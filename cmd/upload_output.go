@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	outputFormat string
+	outputIndent int
+	outputRoot   string
+)
+
+// entry is a speaker or team member as it is published on the website.
+type entry interface {
+	name() string
+	imageURL() string
+	markdown() string
+}
+
+type speakerEntry struct {
+	Name        string       `yaml:"name" json:"name"`
+	Position    string       `yaml:"position" json:"position"`
+	Description folded       `yaml:"description" json:"description"`
+	Image       speakerImage `yaml:"image" json:"image"`
+}
+
+type speakerImage struct {
+	Src string `yaml:"src" json:"src"`
+	Alt string `yaml:"alt" json:"alt"`
+}
+
+type teamEntry struct {
+	Name     string `yaml:"name" json:"name"`
+	Position string `yaml:"position" json:"position"`
+	Linkedin string `yaml:"linkedin" json:"linkedin"`
+	Src      string `yaml:"src" json:"src"`
+}
+
+// folded is a string written as folded block scalar (>-) to yaml.
+type folded string
+
+func (f folded) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.FoldedStyle, Value: string(f)}, nil
+}
+
+func (e speakerEntry) name() string     { return e.Name }
+func (e speakerEntry) imageURL() string { return e.Image.Src }
+func (e teamEntry) name() string        { return e.Name }
+func (e teamEntry) imageURL() string    { return e.Src }
+
+func (e speakerEntry) markdown() string {
+	return fmt.Sprintf("## %s\n\n_%s_\n\n![%s](%s)\n\n%s\n", e.Name, e.Position, e.Image.Alt, e.Image.Src, e.Description)
+}
+
+func (e teamEntry) markdown() string {
+	name := e.Name
+	if e.Linkedin != "" {
+		name = fmt.Sprintf("[%s](%s)", e.Name, e.Linkedin)
+	}
+
+	return fmt.Sprintf("## %s\n\n_%s_\n\n![%s](%s)\n", name, e.Position, e.Name, e.Src)
+}
+
+// addOutputFlags adds the flags that control how the entries are printed.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputFormat, "format", "yaml", "Output format: yaml, json or markdown")
+	cmd.Flags().IntVar(&outputIndent, "indent", 0, "Indentation of yaml and json (default 'indent' of the edition or 2)")
+	cmd.Flags().StringVar(&outputRoot, "root-key", "", "Nest the entries under this key, e.g. speakers.items (default 'root_key' of the edition)")
+}
+
+// outputSettings applies the output flags to the settings of the edition.
+func outputSettings(cmd *cobra.Command, settings importSettings) (int, string) {
+	indent, root := settings.Indent, settings.RootKey
+	if cmd.Flags().Changed("indent") {
+		indent = outputIndent
+	}
+	if cmd.Flags().Changed("root-key") {
+		root = outputRoot
+	}
+	if indent <= 0 {
+		indent = 2
+	}
+
+	return indent, root
+}
+
+// writeEntries encodes the entries in the format selected with --format.
+// With a root key, yaml and json are nested under the keys of its path.
+func writeEntries(w io.Writer, entries []entry, indent int, root string) error {
+	var out interface{} = entries
+	if root != "" {
+		keys := strings.Split(root, ".")
+		for i := len(keys) - 1; i >= 0; i-- {
+			out = map[string]interface{}{keys[i]: out}
+		}
+	}
+
+	switch outputFormat {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(indent)
+		if err := enc.Encode(out); err != nil {
+			return errors.Wrap(err, "encode yaml")
+		}
+
+		return enc.Close()
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", strings.Repeat(" ", indent))
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(out); err != nil {
+			return errors.Wrap(err, "encode json")
+		}
+
+		return nil
+
+	case "markdown":
+		for i, e := range entries {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprint(w, e.markdown())
+		}
+
+		return nil
+
+	default:
+		return errors.Errorf("unsupported output format %q", outputFormat)
+	}
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	publishEntry  bool
+	publishBranch string
+)
+
+// websiteRepo is the repository of the website, configured in the 'website'
+// section.
+type websiteRepo struct {
+	Owner string `mapstructure:"owner"`
+	Repo  string `mapstructure:"repo"`
+	// Base is the branch the pull requests are opened against (default main).
+	Base string `mapstructure:"base"`
+}
+
+// addPublishFlags adds the flags to open a pull request on the website
+// repository instead of printing the entries.
+func addPublishFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&publishEntry, "publish", false, "Commit the entries to the 'data_file' of the edition in the website repository and open or update a pull request")
+	cmd.Flags().StringVar(&publishBranch, "branch", "", "Branch of the pull request (default snctl/<kind>-<edition>)")
+	cmd.MarkFlagsMutuallyExclusive("publish", "write-to")
+	cmd.MarkFlagsMutuallyExclusive("publish", "diff")
+	cmd.MarkFlagsMutuallyExclusive("publish", "validate")
+}
+
+func websiteSettings() (websiteRepo, error) {
+	w := websiteRepo{}
+	if err := viper.UnmarshalKey(configKey("website"), &w); err != nil {
+		return w, errors.Wrap(err, "parse website")
+	}
+
+	if w.Owner == "" || w.Repo == "" {
+		return w, errors.New("no website repository configured, set website.owner and website.repo")
+	}
+
+	if w.Base == "" {
+		w.Base = "main"
+	}
+
+	return w, nil
+}
+
+// publishCommitTrailer marks the commits created by publish. Other commits on
+// the branch of the pull request are never overwritten.
+const publishCommitTrailer = "Generated-by: snctl"
+
+// publishEntries merges the entries into the data file of the website
+// repository and commits the result to the branch of the pull request. An
+// existing branch is only updated if all of its commits were created by
+// publish, the new commit is added on top of them.
+func publishEntries(ctx context.Context, kind string, settings importSettings, entries []entry, indent int, root string) error {
+	w, err := websiteSettings()
+	if err != nil {
+		return err
+	}
+
+	if settings.DataFile == "" {
+		return errors.Errorf("no data_file configured for the %s of edition %s", kind, settings.Name)
+	}
+
+	path, err := listPath(root)
+	if err != nil {
+		return err
+	}
+
+	branch := publishBranch
+	if branch == "" {
+		branch = fmt.Sprintf("snctl/%s-%s", kind, settings.Name)
+	}
+
+	client := newGithubClient()
+
+	base, _, err := client.Git.GetRef(ctx, w.Owner, w.Repo, "heads/"+w.Base)
+	if err != nil {
+		return errors.Wrapf(err, "get branch %s", w.Base)
+	}
+
+	// the pull request is compared to the base branch, the new commit is
+	// added to the head of the branch if it exists already
+	parentSHA := base.GetObject().GetSHA()
+
+	head, res, err := client.Git.GetRef(ctx, w.Owner, w.Repo, "heads/"+branch)
+	exists := true
+	switch {
+	case res != nil && res.StatusCode == http.StatusNotFound:
+		exists = false
+
+	case err != nil:
+		return errors.Wrapf(err, "get branch %s", branch)
+
+	default:
+		comparison, _, err := client.Repositories.CompareCommits(ctx, w.Owner, w.Repo, parentSHA, head.GetObject().GetSHA(), nil)
+		if err != nil {
+			return errors.Wrapf(err, "compare %s with %s", branch, w.Base)
+		}
+
+		// a branch that was merged already starts again from the base
+		if len(comparison.Commits) > 0 {
+			parentSHA = head.GetObject().GetSHA()
+		}
+
+		for _, c := range comparison.Commits {
+			if !strings.Contains(c.GetCommit().GetMessage(), publishCommitTrailer) {
+				return errors.Errorf("branch %s contains commit %.7s that wasn't created by snctl, merge or delete the pull request first, or publish to another --branch", branch, c.GetSHA())
+			}
+		}
+	}
+
+	parent, _, err := client.Git.GetCommit(ctx, w.Owner, w.Repo, parentSHA)
+	if err != nil {
+		return errors.Wrap(err, "get parent commit")
+	}
+
+	// the changes in the description are the ones compared to the base branch
+	doc, err := readWebsiteFile(ctx, client, w, settings.DataFile, base.GetObject().GetSHA())
+	if err != nil {
+		return err
+	}
+
+	changes, err := functions.MergeEntries(doc, path, entryValues(entries), pruneEntry)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 && !exists {
+		fmt.Fprintf(os.Stderr, "=> %s is up to date, nothing to publish\n", settings.DataFile)
+		return nil
+	}
+
+	if exists {
+		if doc, err = readWebsiteFile(ctx, client, w, settings.DataFile, parentSHA); err != nil {
+			return err
+		}
+
+		if _, err := functions.MergeEntries(doc, path, entryValues(entries), pruneEntry); err != nil {
+			return err
+		}
+	}
+
+	data, err := functions.EncodeDocument(doc, indent)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Update %s of %s", kindTitle(kind), settings.Name)
+
+	blob, _, err := client.Git.CreateBlob(ctx, w.Owner, w.Repo, &github.Blob{
+		Content:  github.String(string(data)),
+		Encoding: github.String("utf-8"),
+	})
+	if err != nil {
+		return errors.Wrap(err, "create blob")
+	}
+
+	tree, _, err := client.Git.CreateTree(ctx, w.Owner, w.Repo, parent.GetTree().GetSHA(), []*github.TreeEntry{
+		{Path: github.String(settings.DataFile), Mode: github.String("100644"), Type: github.String("blob"), SHA: blob.SHA},
+	})
+	if err != nil {
+		return errors.Wrap(err, "create tree")
+	}
+
+	// the branch is up to date if the entries didn't change since the last
+	// publish, only the pull request is updated then
+	if tree.GetSHA() != parent.GetTree().GetSHA() {
+		commit, _, err := client.Git.CreateCommit(ctx, w.Owner, w.Repo, &github.Commit{
+			Message: github.String(title + "\n\n" + publishCommitTrailer),
+			Tree:    tree,
+			Parents: []*github.Commit{{SHA: parent.SHA}},
+		}, nil)
+		if err != nil {
+			return errors.Wrap(err, "create commit")
+		}
+
+		ref := &github.Reference{
+			Ref:    github.String("refs/heads/" + branch),
+			Object: &github.GitObject{SHA: commit.SHA},
+		}
+
+		if exists {
+			_, _, err = client.Git.UpdateRef(ctx, w.Owner, w.Repo, ref, false)
+		} else {
+			_, _, err = client.Git.CreateRef(ctx, w.Owner, w.Repo, ref)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "push branch %s", branch)
+		}
+	}
+
+	body := pullRequestBody(kind, settings, changes, entries)
+
+	pulls, _, err := client.PullRequests.List(ctx, w.Owner, w.Repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  w.Owner + ":" + branch,
+		Base:  w.Base,
+	})
+	if err != nil {
+		return errors.Wrap(err, "list pull requests")
+	}
+
+	var pr *github.PullRequest
+	if len(pulls) > 0 {
+		pr, _, err = client.PullRequests.Edit(ctx, w.Owner, w.Repo, pulls[0].GetNumber(), &github.PullRequest{
+			Title: github.String(title),
+			Body:  github.String(body),
+		})
+		if err != nil {
+			return errors.Wrap(err, "update pull request")
+		}
+		fmt.Fprintf(os.Stderr, "=> updated pull request %s\n", pr.GetHTMLURL())
+	} else {
+		pr, _, err = client.PullRequests.Create(ctx, w.Owner, w.Repo, &github.NewPullRequest{
+			Title: github.String(title),
+			Head:  github.String(branch),
+			Base:  github.String(w.Base),
+			Body:  github.String(body),
+		})
+		if err != nil {
+			return errors.Wrap(err, "create pull request")
+		}
+		fmt.Fprintf(os.Stderr, "=> opened pull request %s\n", pr.GetHTMLURL())
+	}
+
+	return nil
+}
+
+// readWebsiteFile parses the file at the commit of the website repository. A
+// missing file is an empty document.
+func readWebsiteFile(ctx context.Context, client *github.Client, w websiteRepo, path, sha string) (*yaml.Node, error) {
+	content := ""
+	file, _, res, err := client.Repositories.GetContents(ctx, w.Owner, w.Repo, path,
+		&github.RepositoryContentGetOptions{Ref: sha})
+	switch {
+	case res != nil && res.StatusCode == http.StatusNotFound:
+		// the data file is created
+
+	case err != nil:
+		return nil, errors.Wrapf(err, "get %s", path)
+
+	default:
+		if content, err = file.GetContent(); err != nil {
+			return nil, errors.Wrapf(err, "decode %s", path)
+		}
+	}
+
+	doc, err := functions.ParseDocument([]byte(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", path)
+	}
+
+	return doc, nil
+}
+
+// pullRequestBody summarizes the changes and links the images of the people
+// that were added or changed.
+func pullRequestBody(kind string, settings importSettings, changes []functions.EntryChange, entries []entry) string {
+	images := map[string]string{}
+	for _, e := range entries {
+		images[e.name()] = e.imageURL()
+	}
+
+	sections := []struct {
+		kind, title string
+	}{
+		{functions.EntryAdded, "Added"},
+		{functions.EntryChanged, "Changed"},
+		{functions.EntryRemoved, "Removed"},
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Updates the %s of %s in `%s`, generated by `snctl upload %s`.\n", kindTitle(kind), settings.Name, settings.DataFile, kind)
+
+	for _, s := range sections {
+		lines := []string{}
+		for _, c := range changes {
+			if c.Kind != s.kind {
+				continue
+			}
+
+			line := "- " + c.Name
+			if c.Kind == functions.EntryChanged {
+				fields := []string{}
+				for _, f := range c.Fields {
+					fields = append(fields, "`"+f.Field+"`")
+				}
+				line += ": " + strings.Join(fields, ", ")
+			}
+			if url := images[c.Name]; url != "" && c.Kind != functions.EntryRemoved {
+				line += fmt.Sprintf(" ([image](%s))", url)
+			}
+
+			lines = append(lines, line)
+		}
+
+		if len(lines) > 0 {
+			fmt.Fprintf(b, "\n### %s (%d)\n\n%s\n", s.title, len(lines), strings.Join(lines, "\n"))
+		}
+	}
+
+	return b.String()
+}
+
+func kindTitle(kind string) string {
+	if kind == "team" {
+		return "team members"
+	}
+
+	return kind + "s"
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeWebsite is a minimal fake of the github api endpoints used by publish.
+type fakeWebsite struct {
+	// branch is the head of the pull request branch, empty if it doesn't
+	// exist, and branchMessage the message of its commits.
+	branch        string
+	branchMessage string
+	pulls         int
+
+	commitParents []string
+	refUpdates    []map[string]interface{}
+	refCreates    int
+	pullEdits     int
+	pullCreates   int
+}
+
+func (f *fakeWebsite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const repo = "/repos/sn/website"
+
+	body := map[string]interface{}{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	switch path := r.URL.Path; {
+	case path == repo+"/git/ref/heads/main":
+		fmt.Fprint(w, `{"ref":"refs/heads/main","object":{"sha":"base"}}`)
+
+	case path == repo+"/git/ref/heads/snctl/speaker-2025":
+		if f.branch == "" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"ref":"refs/heads/snctl/speaker-2025","object":{"sha":%q}}`, f.branch)
+
+	case strings.HasPrefix(path, repo+"/compare/"):
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"commits": []interface{}{map[string]interface{}{"sha": f.branch, "commit": map[string]string{"message": f.branchMessage}}},
+		})
+
+	case strings.HasPrefix(path, repo+"/git/commits/") && r.Method == http.MethodGet:
+		sha := strings.TrimPrefix(path, repo+"/git/commits/")
+		fmt.Fprintf(w, `{"sha":%q,"tree":{"sha":"tree-%s"}}`, sha, sha)
+
+	case path == repo+"/contents/data/speakers.yaml":
+		content := "speakers:\n  items:\n    - name: Jane Doe\n      position: CEO\n"
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"type": "file", "encoding": "base64", "content": base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+
+	case path == repo+"/git/blobs":
+		fmt.Fprint(w, `{"sha":"blob"}`)
+
+	case path == repo+"/git/trees":
+		fmt.Fprint(w, `{"sha":"tree-new"}`)
+
+	case path == repo+"/git/commits":
+		for _, p := range body["parents"].([]interface{}) {
+			f.commitParents = append(f.commitParents, p.(string))
+		}
+		fmt.Fprint(w, `{"sha":"new"}`)
+
+	case path == repo+"/git/refs":
+		f.refCreates++
+		fmt.Fprint(w, `{"ref":"refs/heads/snctl/speaker-2025","object":{"sha":"new"}}`)
+
+	case path == repo+"/git/refs/heads/snctl/speaker-2025":
+		f.refUpdates = append(f.refUpdates, body)
+		fmt.Fprint(w, `{"ref":"refs/heads/snctl/speaker-2025","object":{"sha":"new"}}`)
+
+	case path == repo+"/pulls" && r.Method == http.MethodGet:
+		if f.pulls == 0 {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"number":1}]`)
+
+	case path == repo+"/pulls":
+		f.pullCreates++
+		fmt.Fprint(w, `{"number":1}`)
+
+	case path == repo+"/pulls/1":
+		f.pullEdits++
+		fmt.Fprint(w, `{"number":1}`)
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func publishTestEntries(t *testing.T, fake *fakeWebsite) error {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("github_token", "token")
+	viper.Set("github_url", server.URL)
+	viper.Set("website", map[string]interface{}{"owner": "sn", "repo": "website"})
+
+	settings := importSettings{Name: "2025", DataFile: "data/speakers.yaml"}
+	entries := []entry{
+		speakerEntry{Name: "Jane Doe", Position: "CFO", Image: speakerImage{Src: "https://example.com/jane.png"}},
+	}
+
+	return publishEntries(context.Background(), "speaker", settings, entries, 2, "speakers.items")
+}
+
+func TestPublishEntriesNewBranch(t *testing.T) {
+	fake := &fakeWebsite{}
+	if err := publishTestEntries(t, fake); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.refCreates != 1 || len(fake.refUpdates) != 0 {
+		t.Errorf("created %d and updated %d refs, want a new branch", fake.refCreates, len(fake.refUpdates))
+	}
+	if len(fake.commitParents) != 1 || fake.commitParents[0] != "base" {
+		t.Errorf("commit parents = %v, want [base]", fake.commitParents)
+	}
+	if fake.pullCreates != 1 {
+		t.Errorf("created %d pull requests, want 1", fake.pullCreates)
+	}
+}
+
+// Publishing again adds a commit on top of the branch without force.
+func TestPublishEntriesExistingBranch(t *testing.T) {
+	fake := &fakeWebsite{branch: "head", branchMessage: "Update speakers of 2025\n\n" + publishCommitTrailer, pulls: 1}
+	if err := publishTestEntries(t, fake); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.commitParents) != 1 || fake.commitParents[0] != "head" {
+		t.Errorf("commit parents = %v, want [head]", fake.commitParents)
+	}
+	if len(fake.refUpdates) != 1 || fake.refUpdates[0]["force"] != false {
+		t.Errorf("ref updates = %v, want one without force", fake.refUpdates)
+	}
+	if fake.pullEdits != 1 || fake.pullCreates != 0 {
+		t.Errorf("edited %d and created %d pull requests, want the existing one updated", fake.pullEdits, fake.pullCreates)
+	}
+}
+
+// Commits pushed by someone else are never overwritten.
+func TestPublishEntriesForeignCommit(t *testing.T) {
+	fake := &fakeWebsite{branch: "head", branchMessage: "Fix typo", pulls: 1}
+	if err := publishTestEntries(t, fake); err == nil {
+		t.Fatal("published to a branch with a foreign commit")
+	}
+
+	if len(fake.commitParents) != 0 || len(fake.refUpdates) != 0 || fake.pullEdits != 0 {
+		t.Errorf("changed the repository: commits %v, ref updates %v, pull edits %d", fake.commitParents, fake.refUpdates, fake.pullEdits)
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var (
+	stateFile      string
+	resumeRun      bool
+	onlyFailedRows bool
+)
+
+// runState is written after every import, so a later run can retry the rows
+// that failed without uploading the others again.
+type runState struct {
+	Kind    string              `json:"kind"`
+	Edition string              `json:"edition"`
+	Time    time.Time           `json:"time"`
+	Rows    map[string]rowState `json:"rows"`
+}
+
+// rowState is the result of a row, keyed by the name in the row.
+type rowState struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Image  string `json:"image,omitempty"`
+}
+
+// skipError marks rows that are skipped instead of failed.
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+// importRun processes the rows of an import. Failing rows don't stop the
+// import, they are collected and reported by finish.
+type importRun struct {
+	settings importSettings
+	path     string
+	previous *runState
+	state    *runState
+	status   *statusWriter
+	failed   []string
+}
+
+// addRunFlags adds the flags to retry the rows of a previous run.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&stateFile, "state-file", "", "File the result of every row is written to (default startup_nights_<kind>_state.json next to the config)")
+	cmd.Flags().BoolVar(&resumeRun, "resume", false, "Reuse the rows that were uploaded or published by the previous run and import the others")
+	cmd.Flags().BoolVar(&onlyFailedRows, "only-failed", false, "Only retry the rows that failed in the previous run")
+}
+
+func newImportRun(kind string, settings importSettings, status *statusWriter) (*importRun, error) {
+	path := stateFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), "startup_nights_"+kind+"_state.json")
+	}
+
+	r := &importRun{
+		settings: settings,
+		path:     path,
+		status:   status,
+		state:    &runState{Kind: kind, Edition: settings.Name, Rows: map[string]rowState{}},
+	}
+
+	if !resumeRun && !onlyFailedRows {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read state of the previous run")
+	}
+
+	r.previous = &runState{}
+	if err := json.Unmarshal(data, r.previous); err != nil {
+		return nil, errors.Wrap(err, "parse state of the previous run")
+	}
+
+	if r.previous.Kind != kind || r.previous.Edition != settings.Name {
+		return nil, errors.Errorf("the previous run imported %s of edition %s", r.previous.Kind, r.previous.Edition)
+	}
+
+	return r, nil
+}
+
+// process imports a single row with upload, unless the row is incomplete or
+// was already uploaded by the previous run. It returns the url of the image
+// and whether the row is part of the output.
+func (r *importRun) process(row functions.Row, upload func() (string, error)) (string, bool) {
+	if missing := row.Missing(r.settings.Columns); len(missing) > 0 {
+		if !row.Empty() {
+			r.record(row, rowState{Status: statusSkipped, Reason: "missing " + strings.Join(missing, ", ")})
+		}
+		return "", false
+	}
+
+	key := strings.ToLower(rowName(row))
+
+	if r.previous != nil {
+		prev, ok := r.previous.Rows[key]
+
+		if ok && (prev.Status == statusUploaded || prev.Status == statusPublished) {
+			r.state.Rows[key] = prev
+			return prev.Image, true
+		}
+
+		if onlyFailedRows && (!ok || prev.Status != statusError) {
+			if ok {
+				r.state.Rows[key] = prev
+			}
+			return "", false
+		}
+	}
+
+	url, err := upload()
+
+	var skip skipError
+	switch {
+	case errors.As(err, &skip):
+		r.record(row, rowState{Status: statusSkipped, Reason: skip.reason})
+		return "", false
+
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "failed to import %s (row %d): %v\n", rowName(row), row.Number, err)
+		r.failed = append(r.failed, fmt.Sprintf("row %d %s: %v", row.Number, rowName(row), err))
+		r.record(row, rowState{Status: statusError, Reason: err.Error()})
+		return "", false
+	}
+
+	r.record(row, rowState{Status: statusUploaded, Image: url})
+
+	return url, true
+}
+
+func (r *importRun) record(row functions.Row, s rowState) {
+	s.Row = row.Number
+	r.state.Rows[strings.ToLower(rowName(row))] = s
+	r.status.add(s.Row, s.Status, s.Reason, s.Image)
+}
+
+// published marks the rows of the output as published, after the entries
+// were written to the website.
+func (r *importRun) published() {
+	r.setOutput(statusPublished, "")
+}
+
+// outputFailed records that the entries of the rows couldn't be written. The
+// images are uploaded, so the rows are reused by --resume and --only-failed.
+func (r *importRun) outputFailed(err error) {
+	r.setOutput(statusUploaded, "write entries: "+err.Error())
+}
+
+// setOutput sets the status of all rows that are part of the output.
+func (r *importRun) setOutput(status, reason string) {
+	for key, s := range r.state.Rows {
+		if s.Status != statusUploaded && s.Status != statusPublished {
+			continue
+		}
+
+		s.Status, s.Reason = status, reason
+		r.state.Rows[key] = s
+		r.status.add(s.Row, s.Status, s.Reason, s.Image)
+	}
+}
+
+// finish writes the status back to the sheet and the state file, after the
+// entries were written. A diff doesn't publish anything, so its state isn't
+// kept.
+func (r *importRun) finish() error {
+	if err := r.status.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "write status to sheet: %v\n", err)
+	}
+
+	if diffFile != "" {
+		return nil
+	}
+
+	r.state.Time = time.Now().UTC()
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encode run state")
+	}
+
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return errors.Wrap(err, "write run state")
+	}
+
+	return nil
+}
+
+// report prints a summary of the failed rows and returns an error if there
+// are any.
+func (r *importRun) report() error {
+	if len(r.failed) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "\n=> %d row(s) failed, retry them with --only-failed:\n", len(r.failed))
+	for _, failure := range r.failed {
+		fmt.Fprintf(os.Stderr, "   %s\n", failure)
+	}
+
+	return errors.Errorf("%d row(s) failed", len(r.failed))
+}
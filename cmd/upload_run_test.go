@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/startup-nights/snctl/pkg/functions"
+)
+
+var testTeamColumns = functions.ColumnMapping{
+	"first_name": {Header: "First name", Required: true},
+	"last_name":  {Header: "Last name"},
+	"position":   {Header: "Position"},
+	"linkedin":   {Header: "LinkedIn", Required: true},
+	"image":      {Header: "Image", Required: true},
+}
+
+// Rows are uploaded by process and only published once the entries were
+// written.
+func TestImportRunStatus(t *testing.T) {
+	table, err := functions.MapColumns([][]string{
+		{"First name", "Last name", "Position", "LinkedIn", "Image"},
+		{"Jane", "Doe", "CEO", "https://linkedin.com/in/jane", "jane.png"},
+	}, testTeamColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &statusWriter{}
+	run := &importRun{
+		settings: importSettings{Columns: testTeamColumns},
+		status:   w,
+		state:    &runState{Rows: map[string]rowState{}},
+	}
+
+	url, ok := run.process(table.Rows[0], func() (string, error) { return "https://example.com/jane.png", nil })
+	if !ok || url != "https://example.com/jane.png" {
+		t.Fatalf("process = %q, %v", url, ok)
+	}
+
+	check := func(status, reason string) {
+		t.Helper()
+
+		if s := run.state.Rows["jane doe"]; s.Status != status || s.Reason != reason || s.Image != url {
+			t.Errorf("state = %+v, want %s %q", s, status, reason)
+		}
+		if len(w.statuses) != 1 || w.statuses[0].Status != status || w.statuses[0].Row != 2 {
+			t.Errorf("sheet status = %+v, want %s in row 2", w.statuses, status)
+		}
+	}
+
+	check(statusUploaded, "")
+
+	run.outputFailed(errors.New("push branch"))
+	check(statusUploaded, "write entries: push branch")
+
+	run.published()
+	check(statusPublished, "")
+}
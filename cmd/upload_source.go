@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"google.golang.org/api/sheets/v4"
+)
+
+var (
+	sheetID    string
+	sheetRange string
+)
+
+// addSourceFlags adds the flags to read the rows from a google sheet instead
+// of a csv file. The sheets api can be redirected with 'sheets_url' in the
+// config, for example to a local stub.
+func addSourceFlags(cmd *cobra.Command, kind string) {
+	cmd.Flags().StringVar(&sheetID, "sheet", "", "Id of the google sheet to read from instead of --csv (default 'sheet' of the edition)")
+	cmd.Flags().StringVar(&sheetRange, "range", "", "Tab or range (A1 notation) of the sheet (default the first tab)")
+
+	cmd.Flags().BoolVar(&writeStatus, "write-status", true, "Write the import status back to the sheet, if the edition configures the status columns")
+
+	requireScopesIf(cmd, func(cmd *cobra.Command) bool {
+		return importSheet(cmd, kind) != ""
+	}, "sheets", sheets.SpreadsheetsReadonlyScope)
+	requireScopesIf(cmd, func(cmd *cobra.Command) bool {
+		return writesStatus(cmd, kind)
+	}, "sheets", sheets.SpreadsheetsScope)
+}
+
+// importSheet returns the id of the sheet to read from, or an empty string
+// if the rows are read from --csv.
+func importSheet(cmd *cobra.Command, kind string) string {
+	if sheetID != "" || csvFile != "" {
+		return sheetID
+	}
+
+	settings, err := editionSettings(cmd, kind)
+	if err != nil {
+		return ""
+	}
+
+	return settings.Sheet
+}
+
+// readRecords reads the records of the import from the sheet or the csv file
+// and returns where the records start in the sheet.
+func readRecords(cmd *cobra.Command, kind string) ([][]string, functions.SheetRange, error) {
+	if id := importSheet(cmd, kind); id != "" {
+		readRange := sheetRange
+		if readRange == "" {
+			readRange = "A:ZZ"
+		}
+
+		srv := functions.NewSheetsClient(googleAuth("sheets_token"), configValue("sheets_url"))
+
+		return functions.ReadSheet(srv, id, readRange)
+	}
+
+	if csvFile == "" {
+		return nil, functions.SheetRange{}, errors.New("either --csv or --sheet is required")
+	}
+
+	data, err := os.ReadFile(csvFile)
+	if err != nil {
+		return nil, functions.SheetRange{}, errors.Wrap(err, "read csv")
+	}
+
+	records, err := csv.NewReader(bytes.NewBuffer(data)).ReadAll()
+	if err != nil {
+		return nil, functions.SheetRange{}, errors.Wrap(err, "parse csv")
+	}
+
+	return records, functions.SheetRange{Row: 1}, nil
+}
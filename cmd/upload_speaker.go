@@ -1,8 +1,7 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/csv"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -10,14 +9,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"text/template"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/startup-nights/snctl/pkg/functions"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
@@ -26,45 +24,58 @@ import (
 var (
 	speakerCmd = &cobra.Command{
 		Use:   "speaker",
-		Short: "Upload speakers from csv file",
+		Short: "Upload speakers from a csv file or google sheet",
+		Long: `The columns are mapped by their header text, see the 'columns' of the
+edition in the config. The header row is detected automatically. Speakers
+without a value in a required column are skipped.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			data, err := os.ReadFile(csvFile)
+			settings, err := editionSettings(cmd, "speaker")
 			if err != nil {
-				log.Printf("readfile: %v", err)
 				log.Fatal(err)
 			}
 
-			records, err := csv.NewReader(bytes.NewBuffer(data)).ReadAll()
+			records, origin, err := readRecords(cmd, "speaker")
 			if err != nil {
-				log.Printf("read csv: %v", err)
 				log.Fatal(err)
 			}
 
-			t, err := template.New("output").Parse(speakerTemplate)
+			table, err := functions.MapColumns(records, settings.Columns)
 			if err != nil {
-				log.Printf("parse template: %v", err)
 				log.Fatal(err)
 			}
 
-			type speaker struct {
-				Name        string
-				Position    string
-				Image       string
-				Description string
+			speakers := []entry{}
+
+			if settings.DriveFolder == "" {
+				log.Fatal("no drive folder configured for the speaker images of this edition")
+			}
+
+			srv := functions.NewDriveClient(googleAuth("drive_token"))
+
+			// get all currently uploaded speaker images
+			files, err := listDriveFolder(cmd.Context(), srv, settings.DriveFolder)
+			if err != nil {
+				log.Printf("list files: %v", err)
+				log.Fatal(err)
 			}
 
-			speakers := []speaker{}
+			if validateImport {
+				if err := validateRows(table, settings, speakerImageCheck(files)); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
 
-			srv := functions.NewDriveClient(
-				viper.GetString("drive_token"),
-				viper.GetString("credentials"),
-			)
+			if len(files) == 0 {
+				log.Fatal("no images found in drive folder")
+				return
+			}
 
 			cfg := functions.SpacesConfig{
-				Bucket: viper.GetString("spaces_bucket"),
-				Region: viper.GetString("spaces_region"),
-				Secret: viper.GetString("spaces_secret"),
-				Key:    viper.GetString("spaces_key"),
+				Bucket: configValue("spaces_bucket"),
+				Region: configValue("spaces_region"),
+				Secret: configValue("spaces_secret"),
+				Key:    configValue("spaces_key"),
 			}
 
 			config := &aws.Config{
@@ -81,136 +92,168 @@ var (
 
 			client := s3.New(session)
 
-			// get all currently uploaded speaker images
-			files, err := srv.Files.List().Q("'11Tqb7iAW8QUpqw2TaSu55LqQ-RhrgEWr' in parents").Do(
-				googleapi.QueryParameter("supportsAllDrives", "True"),
-				googleapi.QueryParameter("includeItemsFromAllDrives", "True"),
-			)
+			status, err := newStatusWriter(cmd, "speaker", settings, table, origin)
 			if err != nil {
-				log.Printf("list files: %v", err)
 				log.Fatal(err)
 			}
-			if len(files.Files) == 0 {
-				log.Fatal("no images found in drive folder")
-				return
+
+			run, err := newImportRun("speaker", settings, status)
+			if err != nil {
+				log.Fatal(err)
 			}
 
-			for i, record := range records {
-				// skip header
-				// skip speakers without name
-				// skip speakers who didn't fill out the form yet
-				// skip speakers who haven't uploaded an image yet
-				if i < 2 || record[0] == "" || record[14] == "" || record[18] == "" {
-					continue
-				}
+			for _, row := range table.Rows {
+				// speakers who didn't fill out the form or haven't uploaded an
+				// image yet are skipped
+				name := row.Get("name")
 
-				var image *drive.File
-				found := false
+				url, ok := run.process(row, func() (string, error) {
+					for _, file := range files {
+						if file.Name != name {
+							continue
+						}
 
-				name := record[0]
-				fmt.Printf("uploading %s\n", name)
+						if diffFile != "" {
+							return functions.ImageURL(speakerImageName(file), settings.Prefix), nil
+						}
 
-				for _, file := range files.Files {
-					if file.Name == name {
-						found = true
-						image = file
+						fmt.Fprintf(os.Stderr, "uploading %s\n", name)
+						return uploadSpeakerImage(srv, client, cfg.Bucket, settings, file)
 					}
-				}
 
-				if !found {
-					fmt.Printf("did not find matching image for %s\n", name)
+					fmt.Fprintf(os.Stderr, "did not find matching image for %s\n", name)
+					return "", skipError{reason: "no matching image in the drive folder"}
+				})
+				if !ok {
 					continue
 				}
 
-				res, err := srv.Files.Get(image.Id).Download(
-					googleapi.QueryParameter("supportsAllDrives", "True"),
-				)
-				if err != nil {
-					log.Printf("download %s %s image: %v", record[0], record[1], err)
-					log.Fatal(err)
-				}
+				speakers = append(speakers, speakerEntry{
+					Name:        name,
+					Position:    row.Get("position"),
+					Description: folded(row.Get("description")),
+					Image:       speakerImage{Src: url, Alt: name},
+				})
+			}
 
-				data, err := io.ReadAll(res.Body)
-				if err != nil {
-					log.Printf("read response body: %v", err)
-					log.Fatal(err)
-				}
+			indent, root := outputSettings(cmd, settings)
+			switch {
+			case diffFile != "":
+				err = diffEntries(speakers, root)
+			case publishEntry:
+				err = publishEntries(cmd.Context(), "speaker", settings, speakers, indent, root)
+			case writeTo != "":
+				err = mergeEntries(speakers, indent, root)
+			default:
+				err = writeEntries(os.Stdout, speakers, indent, root)
+			}
+			// a diff fails if there are changes, nothing was written
+			switch {
+			case err != nil && diffFile == "":
+				run.outputFailed(err)
+			case err == nil && (publishEntry || writeTo != ""):
+				run.published()
+			}
+
+			if err := run.finish(); err != nil {
+				log.Fatal(err)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
 
-				input := filepath.Join(os.TempDir(), "input")
-				output := filepath.Join(os.TempDir(), "output.png")
+			if err := run.report(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+)
 
-				if err := os.WriteFile(input, data, 0644); err != nil {
-					log.Printf("write file: %v", err)
-					log.Fatal(err)
-				}
+// listDriveFolder returns all files in the drive folder.
+func listDriveFolder(ctx context.Context, srv *drive.Service, folder string) ([]*drive.File, error) {
+	files := []*drive.File{}
 
-				if err := exec.Command("convert", input, output).Run(); err != nil {
-					log.Printf("convert: %v", err)
-					log.Fatal(err)
-				}
+	err := srv.Files.List().Q(fmt.Sprintf("'%s' in parents", folder)).
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Pages(ctx, func(list *drive.FileList) error {
+			files = append(files, list.Files...)
+			return nil
+		})
 
-				data, err = os.ReadFile(output)
-				if err != nil {
-					log.Printf("readfile: %v", err)
-					log.Fatal(err)
-				}
+	return files, err
+}
 
-				filename := functions.SimplifyName(image.Name)
+// speakerImageName returns the name the image is uploaded with.
+func speakerImageName(image *drive.File) string {
+	filename := functions.SimplifyName(image.Name)
 
-				switch filepath.Ext(filename) {
-				case "":
-					filename += ".png"
-				default:
-					strings.ReplaceAll(filename, filepath.Ext(filename), ".png")
-				}
+	switch filepath.Ext(filename) {
+	case "":
+		filename += ".png"
+	default:
+		strings.ReplaceAll(filename, filepath.Ext(filename), ".png")
+	}
 
-				data, err = functions.ResizeImage(data, filename, 500, 500)
-				if err != nil {
-					log.Printf("resize image: %v", err)
-					log.Fatal(err)
-				}
+	return filename
+}
 
-				url, err := functions.UploadImage(client, cfg.Bucket, filename, "2024/speaker", data)
-				if err != nil {
-					log.Printf("upload image: %v", err)
-					log.Fatal(err)
-				}
+// uploadSpeakerImage converts the image to png, resizes it and uploads it to
+// spaces. It returns the url of the uploaded image.
+func uploadSpeakerImage(srv *drive.Service, client *s3.S3, bucket string, settings importSettings, image *drive.File) (string, error) {
+	res, err := srv.Files.Get(image.Id).Download(
+		googleapi.QueryParameter("supportsAllDrives", "True"),
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "download image")
+	}
+	defer res.Body.Close()
 
-				speakers = append(speakers, speaker{
-					Name:        record[0],
-					Position:    record[10],
-					Description: record[26],
-					Image:       url,
-				})
-			}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "read response body")
+	}
 
-			var buf bytes.Buffer
+	input := filepath.Join(os.TempDir(), "input")
+	output := filepath.Join(os.TempDir(), "output.png")
 
-			if err := t.Execute(&buf, struct {
-				Speakers []speaker
-			}{
-				Speakers: speakers,
-			}); err != nil {
-				log.Printf("template: %v", err)
-				log.Fatal(err)
-			}
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		return "", errors.Wrap(err, "write file")
+	}
 
-			fmt.Println(buf.String())
-		},
+	if err := exec.Command("convert", input, output).Run(); err != nil {
+		return "", errors.Wrap(err, "convert")
 	}
-)
+
+	data, err = os.ReadFile(output)
+	if err != nil {
+		return "", errors.Wrap(err, "read converted image")
+	}
+
+	filename := speakerImageName(image)
+
+	data, err = functions.ResizeImage(data, filename, settings.Width, settings.Height)
+	if err != nil {
+		return "", errors.Wrap(err, "resize image")
+	}
+
+	url, err := functions.UploadImage(client, bucket, filename, settings.Prefix, data)
+	if err != nil {
+		return "", errors.Wrap(err, "upload image")
+	}
+
+	return url, nil
+}
 
 func init() {
 	uploadCmd.AddCommand(speakerCmd)
+	addEditionFlags(speakerCmd)
+	addSourceFlags(speakerCmd, "speaker")
+	addValidateFlag(speakerCmd)
+	addRunFlags(speakerCmd)
+	addOutputFlags(speakerCmd)
+	addWriteFlags(speakerCmd)
+	addDiffFlag(speakerCmd)
+	addPublishFlags(speakerCmd)
+	requireScopes(speakerCmd, "drive", drive.DriveReadonlyScope)
 }
-
-var speakerTemplate = `
-{{ range .Speakers }}
-            - name: '{{ .Name }}'
-              position: '{{ .Position }}'
-              description: >-
-                {{ .Description }}
-              image:
-                src: '{{ .Image }}'
-                alt: '{{ .Name }}'{{ end }}
-`
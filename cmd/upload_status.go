@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// uploaded rows have their image uploaded, they are published once the
+	// entries were written with --write-to or --publish
+	statusUploaded  = "uploaded"
+	statusPublished = "published"
+	statusSkipped   = "skipped"
+	statusError     = "error"
+)
+
+var writeStatus bool
+
+// statusWriter collects the result of every row and writes them back to the
+// sheet the rows were read from. A nil writer discards the results.
+type statusWriter struct {
+	srv      *sheets.Service
+	id       string
+	origin   functions.SheetRange
+	columns  functions.StatusColumns
+	statuses []functions.RowStatus
+}
+
+// writesStatus reports whether the results of the import are written back.
+func writesStatus(cmd *cobra.Command, kind string) bool {
+	if !writeStatus || validateImport || diffFile != "" || importSheet(cmd, kind) == "" {
+		return false
+	}
+
+	settings, err := editionSettings(cmd, kind)
+
+	return err == nil && settings.Writeback.Status != ""
+}
+
+// newStatusWriter returns a writer for the status columns configured in the
+// edition, or nil if the results aren't written back. The rows and columns of
+// the table are relative to origin, the range the api returned the values for.
+func newStatusWriter(cmd *cobra.Command, kind string, settings importSettings, table *functions.Table, origin functions.SheetRange) (*statusWriter, error) {
+	if !writesStatus(cmd, kind) {
+		return nil, nil
+	}
+
+	column := func(header string) (int, error) {
+		if header == "" {
+			return -1, nil
+		}
+
+		i := table.Column(header)
+		if i < 0 {
+			return -1, errors.Errorf("status column %q not found in the sheet", header)
+		}
+
+		return i, nil
+	}
+
+	w := &statusWriter{
+		srv:    functions.NewSheetsClient(googleAuth("sheets_token"), configValue("sheets_url")),
+		id:     importSheet(cmd, kind),
+		origin: origin,
+	}
+
+	var err error
+	h := settings.Writeback
+	if w.columns.Status, err = column(h.Status); err != nil {
+		return nil, err
+	}
+	if w.columns.Reason, err = column(h.Reason); err != nil {
+		return nil, err
+	}
+	if w.columns.Image, err = column(h.Image); err != nil {
+		return nil, err
+	}
+	if w.columns.Time, err = column(h.Timestamp); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// add sets the status of the row with the number, replacing an earlier one.
+func (w *statusWriter) add(number int, status, reason, image string) {
+	if w == nil {
+		return
+	}
+
+	s := functions.RowStatus{
+		Row:    number,
+		Status: status,
+		Reason: reason,
+		Image:  image,
+		Time:   time.Now(),
+	}
+
+	for i := range w.statuses {
+		if w.statuses[i].Row == number {
+			w.statuses[i] = s
+			return
+		}
+	}
+
+	w.statuses = append(w.statuses, s)
+}
+
+// flush writes the collected results to the sheet.
+func (w *statusWriter) flush() error {
+	if w == nil {
+		return nil
+	}
+
+	if err := functions.WriteStatus(w.srv, w.id, w.origin, w.columns, w.statuses); err != nil {
+		return err
+	}
+	w.statuses = nil
+
+	return nil
+}
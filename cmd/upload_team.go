@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/csv"
 	"fmt"
-	"html/template"
 	"io"
 	"log"
 	"os"
@@ -14,53 +11,53 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/startup-nights/snctl/pkg/functions"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
 
 var (
 	teamCmd = &cobra.Command{
 		Use:   "team",
-		Short: "Upload team members from csv file",
-		Long: `Skips the first two rows automatically. Only prints team members with complete
-information (for example, if someone should be deleted, this does not pop up).`,
+		Short: "Upload team members from a csv file or google sheet",
+		Long: `The columns are mapped by their header text, see the 'columns' of the
+edition in the config. The header row is detected automatically. Only prints
+team members with a value in every required column (for example, if someone
+should be deleted, this does not pop up).`,
 		Run: func(cmd *cobra.Command, args []string) {
-			data, err := os.ReadFile(csvFile)
+			settings, err := editionSettings(cmd, "team")
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			records, err := csv.NewReader(bytes.NewBuffer(data)).ReadAll()
+			records, origin, err := readRecords(cmd, "team")
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			t, err := template.New("output").Parse(teamMemberTemplate)
+			table, err := functions.MapColumns(records, settings.Columns)
 			if err != nil {
 				log.Fatal(err)
 			}
 
-			type member struct {
-				Name     string
-				Position string
-				Linkedin string
-				Image    string
-			}
+			members := []entry{}
 
-			members := []member{}
+			srv := functions.NewDriveClient(googleAuth("drive_token"))
 
-			srv := functions.NewDriveClient(
-				viper.GetString("drive_token"),
-				viper.GetString("credentials"),
-			)
+			if validateImport {
+				if err := validateRows(table, settings, teamImageCheck(srv)); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
 
 			cfg := functions.SpacesConfig{
-				Bucket: viper.GetString("spaces_bucket"),
-				Region: viper.GetString("spaces_region"),
-				Secret: viper.GetString("spaces_secret"),
-				Key:    viper.GetString("spaces_key"),
+				Bucket: configValue("spaces_bucket"),
+				Region: configValue("spaces_region"),
+				Secret: configValue("spaces_secret"),
+				Key:    configValue("spaces_key"),
 			}
 
 			config := &aws.Config{
@@ -76,85 +73,144 @@ information (for example, if someone should be deleted, this does not pop up).`,
 
 			client := s3.New(session)
 
-			for i, record := range records {
-				// skip header + empty lines
-				if i < 2 || record[0] == "" || record[4] == "" || record[5] == "" {
-					continue
-				}
-
-				fmt.Printf("uploading %s %s\n", record[0], record[1])
-
-				id := strings.TrimPrefix(record[5], "https://drive.google.com/file/d/")
-				id = strings.TrimSuffix(id, "/view?usp=sharing")
-				id = strings.TrimSuffix(id, "/view?usp=drive_link")
-
-				file, err := srv.Files.Get(id).Do(
-					googleapi.QueryParameter("supportsAllDrives", "True"),
-				)
-				if err != nil {
-					log.Printf("get %s %s file info: %v", record[0], record[1], err)
-					log.Fatal(err)
-				}
+			status, err := newStatusWriter(cmd, "team", settings, table, origin)
+			if err != nil {
+				log.Fatal(err)
+			}
 
-				res, err := srv.Files.Get(id).Download(
-					googleapi.QueryParameter("supportsAllDrives", "True"),
-				)
-				if err != nil {
-					log.Printf("download %s %s image: %v", record[0], record[1], err)
-					log.Fatal(err)
-				}
+			run, err := newImportRun("team", settings, status)
+			if err != nil {
+				log.Fatal(err)
+			}
 
-				data, err := io.ReadAll(res.Body)
-				if err != nil {
-					log.Printf("read response body: %v", err)
-					log.Fatal(err)
-				}
+			for _, row := range table.Rows {
+				// empty lines and incomplete members are skipped
+				name := rowName(row)
 
-				filename := functions.SimplifyName(file.Name)
+				url, ok := run.process(row, func() (string, error) {
+					if diffFile != "" {
+						file, err := teamImageFile(srv, row.Get("image"))
+						if err != nil {
+							return "", err
+						}
 
-				data, err = functions.ResizeImage(data, filename, 500, 500)
-				if err != nil {
-					log.Printf("resize image: %v", err)
-					log.Fatal(err)
-				}
+						return functions.ImageURL(functions.SimplifyName(file.Name), settings.Prefix), nil
+					}
 
-				url, err := functions.UploadImage(client, cfg.Bucket, filename, "2024/team", data)
-				if err != nil {
-					log.Printf("upload image: %v", err)
-					log.Fatal(err)
+					fmt.Fprintf(os.Stderr, "uploading %s\n", name)
+					return uploadTeamImage(srv, client, cfg.Bucket, settings, row.Get("image"))
+				})
+				if !ok {
+					continue
 				}
 
-				members = append(members, member{
-					Name:     fmt.Sprintf("%s %s", record[0], record[1]),
-					Position: record[3],
-					Linkedin: record[4],
-					Image:    url,
+				members = append(members, teamEntry{
+					Name:     name,
+					Position: row.Get("position"),
+					Linkedin: row.Get("linkedin"),
+					Src:      url,
 				})
 			}
 
-			var buf bytes.Buffer
+			indent, root := outputSettings(cmd, settings)
+			switch {
+			case diffFile != "":
+				err = diffEntries(members, root)
+			case publishEntry:
+				err = publishEntries(cmd.Context(), "team", settings, members, indent, root)
+			case writeTo != "":
+				err = mergeEntries(members, indent, root)
+			default:
+				err = writeEntries(os.Stdout, members, indent, root)
+			}
+			// a diff fails if there are changes, nothing was written
+			switch {
+			case err != nil && diffFile == "":
+				run.outputFailed(err)
+			case err == nil && (publishEntry || writeTo != ""):
+				run.published()
+			}
 
-			if err := t.Execute(&buf, struct {
-				Members []member
-			}{
-				Members: members,
-			}); err != nil {
+			if err := run.finish(); err != nil {
+				log.Fatal(err)
+			}
+			if err != nil {
 				log.Fatal(err)
 			}
 
-			fmt.Println(buf.String())
+			if err := run.report(); err != nil {
+				log.Fatal(err)
+			}
 		},
 	}
 )
 
+// driveFileID returns the id of the file a drive sharing link points to.
+func driveFileID(link string) string {
+	id := strings.TrimPrefix(link, "https://drive.google.com/file/d/")
+	id = strings.TrimSuffix(id, "/view?usp=sharing")
+	id = strings.TrimSuffix(id, "/view?usp=drive_link")
+
+	return id
+}
+
+// teamImageFile returns the info of the file the drive link points to.
+func teamImageFile(srv *drive.Service, link string) (*drive.File, error) {
+	file, err := srv.Files.Get(driveFileID(link)).Do(
+		googleapi.QueryParameter("supportsAllDrives", "True"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "get file info")
+	}
+
+	return file, nil
+}
+
+// uploadTeamImage downloads the image the drive link points to, resizes it
+// and uploads it to spaces. It returns the url of the uploaded image.
+func uploadTeamImage(srv *drive.Service, client *s3.S3, bucket string, settings importSettings, link string) (string, error) {
+	file, err := teamImageFile(srv, link)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := srv.Files.Get(file.Id).Download(
+		googleapi.QueryParameter("supportsAllDrives", "True"),
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "download image")
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "read response body")
+	}
+
+	filename := functions.SimplifyName(file.Name)
+
+	data, err = functions.ResizeImage(data, filename, settings.Width, settings.Height)
+	if err != nil {
+		return "", errors.Wrap(err, "resize image")
+	}
+
+	url, err := functions.UploadImage(client, bucket, filename, settings.Prefix, data)
+	if err != nil {
+		return "", errors.Wrap(err, "upload image")
+	}
+
+	return url, nil
+}
+
 func init() {
 	uploadCmd.AddCommand(teamCmd)
+	addEditionFlags(teamCmd)
+	addSourceFlags(teamCmd, "team")
+	addValidateFlag(teamCmd)
+	addRunFlags(teamCmd)
+	addOutputFlags(teamCmd)
+	addWriteFlags(teamCmd)
+	addDiffFlag(teamCmd)
+	addPublishFlags(teamCmd)
+	requireScopes(teamCmd, "drive", drive.DriveReadonlyScope)
 }
-
-var teamMemberTemplate = `
-{{ range .Members }}
-- name: '{{ .Name }}'
-            position: '{{ .Position }}'
-            linkedin: '{{ .Linkedin }}'
-            src: '{{ .Image }}'{{ end }}
-`
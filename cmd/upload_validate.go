@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+var validateImport bool
+
+// rowCheck returns the problems of a row that are specific to the import.
+type rowCheck func(row functions.Row) []string
+
+// addValidateFlag adds --validate, which checks the rows instead of
+// importing them.
+func addValidateFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&validateImport, "validate", false, "Only check the rows and print a report, nothing is downloaded or uploaded")
+}
+
+// validateRows checks every non-empty row for missing required fields, the
+// shape of the linkedin url, the length of the description and the checks of
+// the import. It prints a table with a line per row and a summary, and
+// returns an error if any row has problems.
+func validateRows(table *functions.Table, settings importSettings, check rowCheck) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROW\tNAME\tRESULT\tPROBLEMS")
+
+	total, failed := 0, 0
+	for _, row := range table.Rows {
+		if row.Empty() {
+			continue
+		}
+		total++
+
+		problems := []string{}
+		if missing := row.Missing(settings.Columns); len(missing) > 0 {
+			problems = append(problems, "missing "+strings.Join(missing, ", "))
+		}
+
+		if link := row.Get("linkedin"); link != "" && !validLinkedin(link) {
+			problems = append(problems, "linkedin is not a profile url")
+		}
+
+		if n := utf8.RuneCountInString(row.Get("description")); n > settings.MaxDescription {
+			problems = append(problems, fmt.Sprintf("description has %d characters (max %d)", n, settings.MaxDescription))
+		}
+
+		problems = append(problems, check(row)...)
+
+		result := "ok"
+		if len(problems) > 0 {
+			result = "error"
+			failed++
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", row.Number, rowName(row), result, strings.Join(problems, "; "))
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "print report")
+	}
+
+	fmt.Printf("\n=> %d row(s), %d ok, %d with errors\n", total, total-failed, failed)
+
+	if failed > 0 {
+		return errors.Errorf("%d row(s) with errors", failed)
+	}
+
+	return nil
+}
+
+// rowName is the name of the speaker or team member in the row.
+func rowName(row functions.Row) string {
+	if name := row.Get("name"); name != "" {
+		return name
+	}
+
+	return strings.TrimSpace(row.Get("first_name") + " " + row.Get("last_name"))
+}
+
+func validLinkedin(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+
+	host := strings.TrimPrefix(u.Host, "www.")
+	if host != "linkedin.com" && !strings.HasSuffix(host, ".linkedin.com") {
+		return false
+	}
+
+	return strings.HasPrefix(u.Path, "/in/") || strings.HasPrefix(u.Path, "/company/")
+}
+
+// speakerImageCheck checks that there is an image with the name of the
+// speaker in the drive folder.
+func speakerImageCheck(files []*drive.File) rowCheck {
+	return func(row functions.Row) []string {
+		for _, file := range files {
+			if file.Name == row.Get("name") {
+				return nil
+			}
+		}
+
+		return []string{"no matching image in the drive folder"}
+	}
+}
+
+// teamImageCheck checks that the drive link of the image can be resolved to
+// an image.
+func teamImageCheck(srv *drive.Service) rowCheck {
+	return func(row functions.Row) []string {
+		link := row.Get("image")
+		if link == "" {
+			return nil
+		}
+
+		id := driveFileID(link)
+		if id == "" || strings.ContainsAny(id, "/?:") {
+			return []string{"image is not a drive file link"}
+		}
+
+		file, err := srv.Files.Get(id).Fields("id", "name", "mimeType").Do(
+			googleapi.QueryParameter("supportsAllDrives", "True"),
+		)
+		if err != nil {
+			return []string{"image can't be resolved: " + err.Error()}
+		}
+
+		if !strings.HasPrefix(file.MimeType, "image/") {
+			return []string{fmt.Sprintf("image %s is not an image (%s)", file.Name, file.MimeType)}
+		}
+
+		return nil
+	}
+}
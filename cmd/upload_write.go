@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	writeTo    string
+	entryPath  string
+	pruneEntry bool
+)
+
+// addWriteFlags adds the flags to merge the entries into the website data
+// file instead of printing them.
+func addWriteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&writeTo, "write-to", "", "Merge the entries into this website data file (yaml) instead of printing them")
+	cmd.Flags().StringVar(&entryPath, "path", "", "Path of the list in the data file, e.g. speakers.items (default --root-key)")
+	cmd.Flags().BoolVar(&pruneEntry, "prune", false, "Remove entries from the data file that are not in the source anymore")
+}
+
+// listPath returns the path of the list of entries in the website data file.
+func listPath(root string) (string, error) {
+	path := entryPath
+	if path == "" {
+		path = root
+	}
+
+	if path == "" {
+		return "", errors.New("the path of the list in the data file is required, set --path")
+	}
+
+	return path, nil
+}
+
+// readDataFile parses the website data file.
+func readDataFile(file string) (*yaml.Node, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "read data file")
+	}
+
+	doc, err := functions.ParseDocument(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", file)
+	}
+
+	return doc, nil
+}
+
+func entryValues(entries []entry) []interface{} {
+	values := make([]interface{}, len(entries))
+	for i, e := range entries {
+		values[i] = e
+	}
+
+	return values
+}
+
+// mergeEntries merges the entries into the website data file and prints what
+// changed.
+func mergeEntries(entries []entry, indent int, root string) error {
+	path, err := listPath(root)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(writeTo)
+	if err != nil {
+		return errors.Wrap(err, "stat data file")
+	}
+
+	doc, err := readDataFile(writeTo)
+	if err != nil {
+		return err
+	}
+
+	changes, err := functions.MergeEntries(doc, path, entryValues(entries), pruneEntry)
+	if err != nil {
+		return err
+	}
+
+	data, err := functions.EncodeDocument(doc, indent)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(writeTo, data, info.Mode().Perm()); err != nil {
+		return errors.Wrap(err, "write data file")
+	}
+
+	for _, c := range changes {
+		fmt.Fprintf(os.Stderr, "%-8s %s\n", c.Kind, c.Name)
+	}
+	fmt.Fprintf(os.Stderr, "=> merged %d entries into %s, %d change(s)\n", len(entries), writeTo, len(changes))
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/startup-nights/snctl/pkg/functions"
+	"golang.org/x/term"
+)
+
+// passphraseEnv can hold the vault passphrase for non-interactive use.
+const passphraseEnv = "SNCTL_VAULT_PASSPHRASE"
+
+// vaultCheckValue is encrypted into 'vault_check' to detect wrong passphrases
+// before anything is decrypted.
+const vaultCheckValue = "snctl-vault"
+
+// secretKeys are the config keys that are encrypted by the vault.
+var secretKeys = []string{
+	"credentials",
+	"gmail_token",
+	"sheets_token",
+	"drive_token",
+	"spaces_secret",
+	"github_token",
+	"service_account",
+	"private_key",
+}
+
+var (
+	vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Encrypt the secrets in the config file",
+		Long: `With the vault enabled ('store: vault'), tokens, client credentials, the
+spaces secret, the github token and the private key are stored encrypted in the config file.
+The key is derived from a passphrase (scrypt) and the values are encrypted
+with nacl/secretbox. The passphrase is asked once per invocation, or read from
+` + passphraseEnv + `.`,
+	}
+
+	vaultLockCmd = &cobra.Command{
+		Use:   "lock",
+		Short: "Enable the vault and encrypt all secrets in the config file",
+		Run: func(cmd *cobra.Command, args []string) {
+			v := &vaultStore{}
+
+			if !viper.IsSet("vault_salt") {
+				passphrase, err := newPassphrase()
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				salt, err := functions.NewVaultSalt()
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				v.vault, err = functions.NewVault(passphrase, salt)
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				check, err := v.vault.Encrypt(vaultCheckValue)
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				viper.Set("vault_salt", salt)
+				viper.Set("vault_check", check)
+			}
+
+			if err := v.unlock(); err != nil {
+				cobra.CheckErr(err)
+			}
+
+			for _, key := range secretConfigKeys() {
+				value := viper.GetString(key)
+				if !viper.IsSet(key) || functions.IsEncrypted(value) || isReference(value) {
+					continue
+				}
+
+				encrypted, err := v.vault.Encrypt(value)
+				if err != nil {
+					cobra.CheckErr(errors.Wrapf(err, "encrypt %s", key))
+				}
+
+				viper.Set(key, encrypted)
+				fmt.Printf("encrypted %s\n", key)
+			}
+
+			viper.Set("store", "vault")
+			if err := viper.WriteConfig(); err != nil {
+				cobra.CheckErr(errors.Wrap(err, "write config"))
+			}
+		},
+	}
+
+	vaultUnlockCmd = &cobra.Command{
+		Use:   "unlock",
+		Short: "Decrypt all secrets in the config file and disable the vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			v := &vaultStore{}
+
+			for _, key := range secretConfigKeys() {
+				value := viper.GetString(key)
+				if !functions.IsEncrypted(value) {
+					continue
+				}
+
+				decrypted, err := v.decrypt(key, value)
+				if err != nil {
+					cobra.CheckErr(err)
+				}
+
+				viper.Set(key, decrypted)
+				fmt.Printf("decrypted %s\n", key)
+			}
+
+			viper.Set("store", "config")
+			if err := viper.WriteConfig(); err != nil {
+				cobra.CheckErr(errors.Wrap(err, "write config"))
+			}
+
+			for _, key := range []string{"vault_salt", "vault_check"} {
+				if err := unsetConfig(key); err != nil {
+					cobra.CheckErr(err)
+				}
+			}
+		},
+	}
+)
+
+// vaultStore keeps secret values encrypted in the config file. The vault is
+// unlocked on the first access to an encrypted value. Values that are still
+// in plain text are returned as they are.
+type vaultStore struct {
+	vault *functions.Vault
+}
+
+func (s *vaultStore) unlock() error {
+	if s.vault != nil {
+		return nil
+	}
+
+	if !viper.IsSet("vault_salt") || !viper.IsSet("vault_check") {
+		return errors.New("the vault is not initialized, run: snctl vault lock")
+	}
+
+	passphrase, err := readPassphrase("vault passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	v, err := functions.NewVault(passphrase, viper.GetString("vault_salt"))
+	if err != nil {
+		return err
+	}
+
+	if check, err := v.Decrypt(viper.GetString("vault_check")); err != nil || check != vaultCheckValue {
+		return errors.New("wrong vault passphrase")
+	}
+
+	s.vault = v
+
+	return nil
+}
+
+func (s *vaultStore) IsSet(key string) bool {
+	return configIsSet(key)
+}
+
+func (s *vaultStore) Get(key string) (string, error) {
+	value, err := s.decrypt(key, configString(key))
+	if err != nil {
+		return "", err
+	}
+
+	value, err = resolveReference(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve %s", key)
+	}
+
+	return value, nil
+}
+
+func (s *vaultStore) decrypt(key, value string) (string, error) {
+	if !functions.IsEncrypted(value) {
+		return value, nil
+	}
+
+	if err := s.unlock(); err != nil {
+		return "", err
+	}
+
+	decrypted, err := s.vault.Decrypt(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "decrypt %s", key)
+	}
+
+	return decrypted, nil
+}
+
+func (s *vaultStore) Set(key, value string) error {
+	if err := checkWritable(key); err != nil {
+		return err
+	}
+
+	// references are kept readable, they don't contain the secret
+	if contains(secretKeys, key) && !isReference(value) {
+		if err := s.unlock(); err != nil {
+			return err
+		}
+
+		var err error
+		value, err = s.vault.Encrypt(value)
+		if err != nil {
+			return errors.Wrapf(err, "encrypt %s", key)
+		}
+	}
+
+	return setConfig(key, value)
+}
+
+func (s *vaultStore) Delete(key string) error {
+	return unsetConfig(configKey(key))
+}
+
+// secretConfigKeys returns the viper keys of all secrets, including the ones
+// in profiles.
+func secretConfigKeys() []string {
+	keys := []string{}
+	for _, key := range secretKeys {
+		keys = append(keys, allConfigKeys(key)...)
+	}
+
+	return keys
+}
+
+// readPassphrase reads the passphrase from the environment or, without
+// echoing it, from the terminal.
+func readPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.Errorf("no terminal to ask for the vault passphrase, set %s", passphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.Wrap(err, "read passphrase")
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// newPassphrase asks for a new passphrase twice.
+func newPassphrase() (string, error) {
+	passphrase, err := readPassphrase("new vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase == "" {
+		return "", errors.New("the passphrase must not be empty")
+	}
+
+	if os.Getenv(passphraseEnv) != "" {
+		return passphrase, nil
+	}
+
+	repeated, err := readPassphrase("repeat vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	if repeated != passphrase {
+		return "", errors.New("passphrases don't match")
+	}
+
+	return passphrase, nil
+}
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultLockCmd)
+	vaultCmd.AddCommand(vaultUnlockCmd)
+}
@@ -0,0 +1,159 @@
+package functions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// headerSearchRows is the number of rows searched for the header row.
+const headerSearchRows = 10
+
+// Column maps a field to the column with the given header text or one of its
+// aliases. Rows without a value in a required column are skipped.
+type Column struct {
+	Header   string   `mapstructure:"header"`
+	Aliases  []string `mapstructure:"aliases"`
+	Required bool     `mapstructure:"required"`
+}
+
+// ColumnMapping maps field names to columns.
+type ColumnMapping map[string]Column
+
+// Row is a single record with its values accessible by field name.
+type Row struct {
+	// Number is the 1-based row number in the csv file or sheet.
+	Number int
+	values map[string]string
+}
+
+// Get returns the trimmed value of the field.
+func (r Row) Get(field string) string {
+	return r.values[field]
+}
+
+// Empty reports whether none of the fields has a value.
+func (r Row) Empty() bool {
+	for _, value := range r.values {
+		if value != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Missing returns the required fields without a value.
+func (r Row) Missing(mapping ColumnMapping) []string {
+	missing := []string{}
+	for _, field := range mapping.fields() {
+		if mapping[field].Required && r.values[field] == "" {
+			missing = append(missing, field)
+		}
+	}
+
+	return missing
+}
+
+// Table is the header and the rows below it.
+type Table struct {
+	Header []string
+	// HeaderRow is the 1-based row number of the header.
+	HeaderRow int
+	Rows      []Row
+}
+
+// Column returns the index of the column with the header text, or -1.
+func (t *Table) Column(header string) int {
+	for i, text := range t.Header {
+		if normalizeHeader(text) == normalizeHeader(header) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// MapColumns detects the header row in records and returns the rows below it.
+// The header row is the first one that contains all required columns. If
+// there is none, the error lists the required fields that couldn't be mapped.
+func MapColumns(records [][]string, mapping ColumnMapping) (*Table, error) {
+	var best map[string]int
+	var unmapped []string
+	header := -1
+
+	for i := 0; i < len(records) && i < headerSearchRows; i++ {
+		index, missing := mapping.match(records[i])
+		if len(missing) == 0 {
+			header, best = i, index
+			break
+		}
+
+		if unmapped == nil || len(missing) < len(unmapped) {
+			unmapped = missing
+		}
+	}
+
+	if header < 0 {
+		return nil, errors.Errorf("no header row with all required columns found, unmapped: %s", strings.Join(unmapped, ", "))
+	}
+
+	table := &Table{Header: records[header], HeaderRow: header + 1}
+	for i, record := range records[header+1:] {
+		row := Row{Number: header + i + 2, values: map[string]string{}}
+		for field, col := range best {
+			if col < len(record) {
+				row.values[field] = strings.TrimSpace(record[col])
+			}
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// match returns the column index of every field found in the header and the
+// required fields that weren't found.
+func (m ColumnMapping) match(header []string) (map[string]int, []string) {
+	columns := map[string]int{}
+	for i, text := range header {
+		if _, ok := columns[normalizeHeader(text)]; !ok {
+			columns[normalizeHeader(text)] = i
+		}
+	}
+
+	index := map[string]int{}
+	missing := []string{}
+	for _, field := range m.fields() {
+		c := m[field]
+		for _, name := range append([]string{c.Header}, c.Aliases...) {
+			if i, ok := columns[normalizeHeader(name)]; ok && name != "" {
+				index[field] = i
+				break
+			}
+		}
+
+		if _, ok := index[field]; !ok && c.Required {
+			missing = append(missing, fmt.Sprintf("%s (%q)", field, c.Header))
+		}
+	}
+
+	return index, missing
+}
+
+func (m ColumnMapping) fields() []string {
+	fields := []string{}
+	for field := range m {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func normalizeHeader(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,64 @@
+package functions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testMapping = ColumnMapping{
+	"name":     {Header: "Name", Aliases: []string{"Full name"}, Required: true},
+	"position": {Header: "Position", Aliases: []string{"Role"}},
+	"image":    {Header: "Image", Required: true},
+}
+
+func TestMapColumns(t *testing.T) {
+	records := [][]string{
+		{"Speaker form 2025"},
+		{},
+		{"Timestamp", "  full   NAME ", "Image", "Role"},
+		{"1.1.2025", " Jane Doe ", "jane.png", "CEO"},
+		{"2.1.2025", "John Doe"},
+	}
+
+	table, err := MapColumns(records, testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.HeaderRow != 3 {
+		t.Errorf("HeaderRow = %d, want 3", table.HeaderRow)
+	}
+	if table.Column("image") != 2 || table.Column("Missing") != -1 {
+		t.Errorf("Column(image) = %d, Column(Missing) = %d", table.Column("image"), table.Column("Missing"))
+	}
+	if len(table.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(table.Rows))
+	}
+
+	jane := table.Rows[0]
+	if jane.Number != 4 || jane.Get("name") != "Jane Doe" || jane.Get("position") != "CEO" || jane.Get("image") != "jane.png" {
+		t.Errorf("row = %+v", jane)
+	}
+	if missing := jane.Missing(testMapping); len(missing) != 0 {
+		t.Errorf("Missing = %v, want none", missing)
+	}
+
+	john := table.Rows[1]
+	if john.Number != 5 || john.Empty() {
+		t.Errorf("row = %+v", john)
+	}
+	if missing := john.Missing(testMapping); !reflect.DeepEqual(missing, []string{"image"}) {
+		t.Errorf("Missing = %v, want [image]", missing)
+	}
+}
+
+func TestMapColumnsUnmapped(t *testing.T) {
+	_, err := MapColumns([][]string{{"Name", "Photo"}}, testMapping)
+	if err == nil {
+		t.Fatal("mapped a header without all required columns")
+	}
+	if !strings.Contains(err.Error(), `image ("Image")`) || strings.Contains(err.Error(), "name") {
+		t.Errorf("error = %v, want only the image column listed", err)
+	}
+}
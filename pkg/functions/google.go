@@ -0,0 +1,154 @@
+package functions
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// TokenSaver persists a token after it has been refreshed.
+type TokenSaver func(*Token) error
+
+// GoogleAuth contains everything needed to authenticate against the google
+// apis, either with a user token or with a service account.
+type GoogleAuth struct {
+	// Credentials are the oauth client credentials (json).
+	Credentials string
+	// Token is the stored user token (json).
+	Token string
+	// Save is called with every refreshed token, it can be nil.
+	Save TokenSaver
+
+	// ServiceAccount is a service account key (json). If set, it is used
+	// instead of the user token.
+	ServiceAccount string
+	// Subject is the user impersonated by the service account with
+	// domain-wide delegation, it is optional.
+	Subject string
+}
+
+func (a GoogleAuth) client(ctx context.Context, scopes ...string) (*http.Client, error) {
+	if a.ServiceAccount != "" {
+		config, err := google.JWTConfigFromJSON([]byte(a.ServiceAccount), scopes...)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse service account key")
+		}
+		config.Subject = a.Subject
+
+		return config.Client(ctx), nil
+	}
+
+	config, err := google.ConfigFromJSON([]byte(a.Credentials), scopes...)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse client credentials")
+	}
+
+	token, err := ParseToken(a.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	src := config.TokenSource(ctx, &token.Token)
+	if a.Save != nil {
+		src = PersistingTokenSource(src, token, a.Save)
+	}
+
+	return oauth2.NewClient(ctx, src), nil
+}
+
+func NewGmailClient(auth GoogleAuth) *gmail.Service {
+	ctx := context.Background()
+
+	client, err := auth.client(ctx, gmail.GmailComposeScope)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return srv
+}
+
+// NewSheetsClient returns a sheets client. The api can be redirected to
+// endpoint, for example to a local stub; an empty endpoint uses google.
+func NewSheetsClient(auth GoogleAuth, endpoint string) *sheets.Service {
+	ctx := context.Background()
+
+	client, err := auth.client(ctx, sheets.SpreadsheetsScope)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if endpoint != "" {
+		if !strings.HasSuffix(endpoint, "/") {
+			endpoint += "/"
+		}
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return srv
+}
+
+// PersistingTokenSource wraps src and hands every token that differs from the
+// last known one to save. The scope of the last token is kept if the refresh
+// response doesn't contain one. Failing to save is logged, but doesn't fail
+// the request that triggered the refresh.
+func PersistingTokenSource(src oauth2.TokenSource, last *Token, save TokenSaver) oauth2.TokenSource {
+	return &persistingTokenSource{
+		src:  src,
+		last: last,
+		save: save,
+	}
+}
+
+type persistingTokenSource struct {
+	mu   sync.Mutex
+	src  oauth2.TokenSource
+	last *Token
+	save TokenSaver
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last != nil && token.AccessToken == s.last.AccessToken && token.RefreshToken == s.last.RefreshToken {
+		return token, nil
+	}
+
+	next := NewToken(token)
+	if next.Scope == "" && s.last != nil {
+		next.Scope = s.last.Scope
+	}
+
+	if err := s.save(next); err != nil {
+		log.Printf("persist refreshed token: %v", err)
+	}
+
+	s.last = next
+
+	return token, nil
+}
@@ -0,0 +1,291 @@
+package functions
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EntryAdded   = "added"
+	EntryChanged = "changed"
+	EntryRemoved = "removed"
+)
+
+// EntryChange describes how a generated entry differs from the one in the
+// document.
+type EntryChange struct {
+	Name   string
+	Kind   string
+	Fields []FieldChange
+}
+
+// FieldChange is a single changed field, nested fields are joined with dots.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug returns the name in lower case with umlauts replaced and everything
+// else than letters and digits replaced by dashes.
+func Slug(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "é", "e", "è", "e", "à", "a").Replace(name)
+
+	return strings.Trim(slugInvalid.ReplaceAllString(name, "-"), "-")
+}
+
+// ParseDocument parses a yaml document. Empty data results in an empty
+// mapping.
+func ParseDocument(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, errors.Wrap(err, "parse yaml")
+	}
+
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	return doc, nil
+}
+
+// EncodeDocument encodes the document with the given indentation.
+func EncodeDocument(doc *yaml.Node, indent int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(doc); err != nil {
+		return nil, errors.Wrap(err, "encode yaml")
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "encode yaml")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MergeEntries merges the entries into the list at path (e.g.
+// speakers.items) of the document. Existing entries are matched by their
+// slug or by the slug of their name. Changed fields of existing entries are updated,
+// new entries are appended and, with prune, entries that aren't part of
+// entries are removed. Everything else in the document, including fields
+// that the entries don't have, order and comments, is kept.
+func MergeEntries(doc *yaml.Node, path string, entries []interface{}, prune bool) ([]EntryChange, error) {
+	list, err := sequenceAt(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]*yaml.Node{}
+	for _, item := range list.Content {
+		for _, key := range entryKeys(item) {
+			if _, ok := existing[key]; !ok {
+				existing[key] = item
+			}
+		}
+	}
+
+	changes := []EntryChange{}
+	seen := map[*yaml.Node]bool{}
+
+	for _, e := range entries {
+		node := &yaml.Node{}
+		if err := node.Encode(e); err != nil {
+			return nil, errors.Wrap(err, "encode entry")
+		}
+
+		var item *yaml.Node
+		for _, key := range entryKeys(node) {
+			if item = existing[key]; item != nil {
+				break
+			}
+		}
+
+		if item == nil {
+			seen[node] = true
+			list.Content = append(list.Content, node)
+			changes = append(changes, EntryChange{Name: field(node, "name"), Kind: EntryAdded})
+			continue
+		}
+
+		seen[item] = true
+
+		if fields := mergeNode(item, node, ""); len(fields) > 0 {
+			changes = append(changes, EntryChange{Name: field(node, "name"), Kind: EntryChanged, Fields: fields})
+		}
+	}
+
+	if prune {
+		kept := []*yaml.Node{}
+		for _, item := range list.Content {
+			if len(entryKeys(item)) > 0 && !seen[item] {
+				changes = append(changes, EntryChange{Name: field(item, "name"), Kind: EntryRemoved})
+				continue
+			}
+
+			kept = append(kept, item)
+		}
+
+		list.Content = kept
+	}
+
+	return changes, nil
+}
+
+// sequenceAt returns the list at the dot separated path, missing keys and an
+// empty list are created.
+func sequenceAt(doc *yaml.Node, path string) (*yaml.Node, error) {
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil, errors.Errorf("%s: parent of %s is not a mapping", path, key)
+		}
+
+		next := value(node, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+
+		node = next
+	}
+
+	switch {
+	case node.Kind == yaml.SequenceNode:
+	case node.Kind == yaml.MappingNode && len(node.Content) == 0:
+		// created above
+		node.Kind, node.Tag = yaml.SequenceNode, "!!seq"
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		node.Kind, node.Tag, node.Value = yaml.SequenceNode, "!!seq", ""
+	default:
+		return nil, errors.Errorf("%s is not a list", path)
+	}
+
+	return node, nil
+}
+
+// mergeNode updates dst with the fields of src and returns what changed.
+func mergeNode(dst, src *yaml.Node, prefix string) []FieldChange {
+	changes := []FieldChange{}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i].Value, src.Content[i+1]
+		name := prefix + key
+
+		old := value(dst, key)
+		switch {
+		case old == nil && emptyNode(val):
+			// a missing field is the same as an empty one
+
+		case old == nil:
+			dst.Content = append(dst.Content, src.Content[i], val)
+			changes = append(changes, FieldChange{Field: name, New: nodeText(val)})
+
+		case old.Kind == yaml.MappingNode && val.Kind == yaml.MappingNode:
+			changes = append(changes, mergeNode(old, val, name+".")...)
+
+		case old.Kind == yaml.ScalarNode && val.Kind == yaml.ScalarNode:
+			if old.Value == val.Value {
+				continue
+			}
+
+			changes = append(changes, FieldChange{Field: name, Old: old.Value, New: val.Value})
+			old.Value, old.Tag = val.Value, val.Tag
+			if strings.Contains(val.Value, "\n") && old.Style != yaml.LiteralStyle {
+				old.Style = val.Style
+			}
+
+		default:
+			if nodeText(old) == nodeText(val) {
+				continue
+			}
+
+			changes = append(changes, FieldChange{Field: name, Old: nodeText(old), New: nodeText(val)})
+			*old = *val
+		}
+	}
+
+	return changes
+}
+
+// value returns the value of key in the mapping, or nil.
+func value(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func field(mapping *yaml.Node, key string) string {
+	if v := value(mapping, key); v != nil {
+		return v.Value
+	}
+
+	return ""
+}
+
+// entryKeys returns the keys that identify an entry: its slug and the slug
+// of its name.
+func entryKeys(entry *yaml.Node) []string {
+	keys := []string{}
+	if slug := field(entry, "slug"); slug != "" {
+		keys = append(keys, slug)
+	}
+	if slug := Slug(field(entry, "name")); slug != "" {
+		keys = append(keys, slug)
+	}
+
+	return keys
+}
+
+// emptyNode reports whether the node is an empty scalar or a mapping with
+// only empty values.
+func emptyNode(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value == ""
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if !emptyNode(n.Content[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func nodeText(n *yaml.Node) string {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+
+	data, err := yaml.Marshal(n)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,202 @@
+package functions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	Name     string    `yaml:"name"`
+	Position string    `yaml:"position"`
+	Image    testImage `yaml:"image"`
+}
+
+type testImage struct {
+	Src string `yaml:"src"`
+}
+
+func TestMergeEntries(t *testing.T) {
+	doc := `speakers:
+  title: Speakers
+  items:
+    # first
+    - name: Anna Müller
+      slug: anna
+      position: CEO
+      image:
+        src: https://example.com/anna.png
+    - name: Ben Meier
+      position: CTO
+      linkedin: https://linkedin.com/in/ben
+      image:
+        src: https://example.com/ben.png
+    - name: Old Person
+      position: Founder
+`
+
+	entries := []interface{}{
+		testEntry{Name: "Anna Müller", Position: "CFO", Image: testImage{Src: "https://example.com/anna.png"}},
+		testEntry{Name: "Ben Meier", Position: "CTO", Image: testImage{Src: "https://example.com/ben-2.png"}},
+		testEntry{Name: "New Person", Position: "Dev"},
+	}
+
+	tests := []struct {
+		name    string
+		prune   bool
+		changes []EntryChange
+		want    string
+	}{
+		{
+			name:  "keep",
+			prune: false,
+			changes: []EntryChange{
+				{Name: "Anna Müller", Kind: EntryChanged, Fields: []FieldChange{{Field: "position", Old: "CEO", New: "CFO"}}},
+				{Name: "Ben Meier", Kind: EntryChanged, Fields: []FieldChange{{Field: "image.src", Old: "https://example.com/ben.png", New: "https://example.com/ben-2.png"}}},
+				{Name: "New Person", Kind: EntryAdded},
+			},
+			want: `speakers:
+  title: Speakers
+  items:
+    # first
+    - name: Anna Müller
+      slug: anna
+      position: CFO
+      image:
+        src: https://example.com/anna.png
+    - name: Ben Meier
+      position: CTO
+      linkedin: https://linkedin.com/in/ben
+      image:
+        src: https://example.com/ben-2.png
+    - name: Old Person
+      position: Founder
+    - name: New Person
+      position: Dev
+      image:
+        src: ""
+`,
+		},
+		{
+			name:  "prune",
+			prune: true,
+			changes: []EntryChange{
+				{Name: "Anna Müller", Kind: EntryChanged, Fields: []FieldChange{{Field: "position", Old: "CEO", New: "CFO"}}},
+				{Name: "Ben Meier", Kind: EntryChanged, Fields: []FieldChange{{Field: "image.src", Old: "https://example.com/ben.png", New: "https://example.com/ben-2.png"}}},
+				{Name: "New Person", Kind: EntryAdded},
+				{Name: "Old Person", Kind: EntryRemoved},
+			},
+			want: `speakers:
+  title: Speakers
+  items:
+    # first
+    - name: Anna Müller
+      slug: anna
+      position: CFO
+      image:
+        src: https://example.com/anna.png
+    - name: Ben Meier
+      position: CTO
+      linkedin: https://linkedin.com/in/ben
+      image:
+        src: https://example.com/ben-2.png
+    - name: New Person
+      position: Dev
+      image:
+        src: ""
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := ParseDocument([]byte(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changes, err := MergeEntries(node, "speakers.items", entries, tt.prune)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", changes, tt.changes)
+			}
+
+			out, err := EncodeDocument(node, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != tt.want {
+				t.Errorf("document:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+// Entries with a custom slug are matched by the slug of their name as well.
+func TestMergeEntriesCustomSlug(t *testing.T) {
+	node, err := ParseDocument([]byte("items:\n  - name: Anna Müller\n    slug: anna\n    position: CEO\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := MergeEntries(node, "items", []interface{}{
+		testEntry{Name: "Anna Müller", Position: "CEO"},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+}
+
+func TestMergeEntriesCreatesPath(t *testing.T) {
+	node, err := ParseDocument(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := MergeEntries(node, "team.items", []interface{}{testEntry{Name: "Jane"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Kind != EntryAdded {
+		t.Errorf("changes = %+v, want one added", changes)
+	}
+
+	out, err := EncodeDocument(node, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "team:\n  items:\n    - name: Jane\n") {
+		t.Errorf("document:\n%s", out)
+	}
+}
+
+func TestMergeEntriesNotAList(t *testing.T) {
+	node, err := ParseDocument([]byte("items: text\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeEntries(node, "items", nil, false); err == nil {
+		t.Error("merged into a scalar")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for in, want := range map[string]string{
+		"Anna Müller":    "anna-mueller",
+		"  José  Ramos ": "jose-ramos",
+		"O'Brien, Conan": "o-brien-conan",
+	} {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
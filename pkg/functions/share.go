@@ -0,0 +1,224 @@
+package functions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// SharedConfig contains config values encrypted for a list of recipients. The
+// values are encrypted with a random data key (nacl/secretbox), which is in
+// turn encrypted for every recipient with nacl/box by the sender.
+type SharedConfig struct {
+	Version    int                        `yaml:"version"`
+	Sender     string                     `yaml:"sender"`
+	Recipients map[string]SharedRecipient `yaml:"recipients"`
+	Payload    string                     `yaml:"payload"`
+}
+
+// SharedRecipient is the data key encrypted for a single recipient.
+type SharedRecipient struct {
+	Key     string `yaml:"key"`
+	DataKey string `yaml:"data_key"`
+}
+
+// GenerateMemberKey returns a new base64 encoded key pair.
+func GenerateMemberKey() (string, string, error) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", errors.Wrap(err, "generate key from random data")
+	}
+
+	return base64.StdEncoding.EncodeToString(pub[:]), base64.StdEncoding.EncodeToString(priv[:]), nil
+}
+
+// PublicKey returns the base64 encoded public key of a private key.
+func PublicKey(privateKey string) (string, error) {
+	priv, err := decodeKey(privateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "decode private key")
+	}
+
+	pub, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return "", errors.Wrap(err, "derive public key")
+	}
+
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// SealShared encrypts the values for the recipients (name to public key).
+func SealShared(values map[string]string, privateKey string, recipients map[string]string) (*SharedConfig, error) {
+	priv, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode private key")
+	}
+
+	sender, err := PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode values")
+	}
+
+	dataKey := new([32]byte)
+	if _, err := rand.Read(dataKey[:]); err != nil {
+		return nil, errors.Wrap(err, "generate data key")
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &SharedConfig{
+		Version:    1,
+		Sender:     sender,
+		Recipients: map[string]SharedRecipient{},
+		Payload:    base64.StdEncoding.EncodeToString(secretbox.Seal(nonce[:], plain, nonce, dataKey)),
+	}
+
+	for name, key := range recipients {
+		pub, err := decodeKey(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode public key of %s", name)
+		}
+
+		nonce, err := newNonce()
+		if err != nil {
+			return nil, err
+		}
+
+		shared.Recipients[name] = SharedRecipient{
+			Key:     key,
+			DataKey: base64.StdEncoding.EncodeToString(box.Seal(nonce[:], dataKey[:], nonce, pub, priv)),
+		}
+	}
+
+	return shared, nil
+}
+
+// Open decrypts the values with the private key of one of the recipients.
+// The sender has to be one of the trusted public keys, the recipients listed
+// in the file can't be trusted as anyone can write them.
+func (s *SharedConfig) Open(privateKey string, trusted []string) (map[string]string, error) {
+	if s.Version != 1 {
+		return nil, errors.Errorf("unsupported version %d", s.Version)
+	}
+
+	priv, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode private key")
+	}
+
+	own, err := PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipient *SharedRecipient
+	for _, r := range s.Recipients {
+		r := r
+		if r.Key == own {
+			recipient = &r
+		}
+	}
+
+	if recipient == nil {
+		return nil, errors.New("the config is not shared with this key")
+	}
+
+	senderKnown := false
+	for _, key := range trusted {
+		senderKnown = senderKnown || key == s.Sender
+	}
+
+	if !senderKnown {
+		return nil, errors.Errorf("the config was shared by the unknown key %s, add it to 'recipients' if you trust it", s.Sender)
+	}
+
+	sender, err := decodeKey(s.Sender)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode sender key")
+	}
+
+	sealed, err := decodeSealed(recipient.DataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := new([24]byte)
+	copy(nonce[:], sealed[:24])
+
+	dataKey, ok := box.Open(nil, sealed[24:], nonce, sender, priv)
+	if !ok || len(dataKey) != 32 {
+		return nil, errors.New("decrypt data key")
+	}
+
+	payload, err := decodeSealed(s.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(nonce[:], payload[:24])
+	key := new([32]byte)
+	copy(key[:], dataKey)
+
+	plain, ok := secretbox.Open(nil, payload[24:], nonce, key)
+	if !ok {
+		return nil, errors.New("decrypt payload")
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, errors.Wrap(err, "decode values")
+	}
+
+	return values, nil
+}
+
+func decodeKey(key string) (*[32]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) != 32 {
+		return nil, errors.Errorf("expected 32 bytes, got %d", len(b))
+	}
+
+	out := new([32]byte)
+	copy(out[:], b)
+
+	return out, nil
+}
+
+func decodeSealed(value string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode encrypted value")
+	}
+
+	if len(b) < 24 {
+		return nil, errors.New("encrypted value is too short")
+	}
+
+	return b, nil
+}
+
+func newNonce() (*[24]byte, error) {
+	nonce := new([24]byte)
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, errors.Wrap(err, "generate nonce")
+	}
+
+	return nonce, nil
+}
@@ -0,0 +1,74 @@
+package functions
+
+import (
+	"testing"
+)
+
+func TestSharedConfigOpen(t *testing.T) {
+	alicePub, alicePriv := mustKey(t)
+	bobPub, bobPriv := mustKey(t)
+	malloryPub, malloryPriv := mustKey(t)
+
+	values := map[string]string{"github_token": "secret"}
+
+	shared, err := SealShared(values, alicePriv, map[string]string{"alice": alicePub, "bob": bobPub})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := shared.Open(bobPriv, []string{alicePub, bobPub})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if got["github_token"] != "secret" {
+		t.Errorf("github_token = %q, want %q", got["github_token"], "secret")
+	}
+
+	if _, err := shared.Open(malloryPriv, []string{alicePub, malloryPub}); err == nil {
+		t.Error("opened a config that isn't shared with the key")
+	}
+
+	if _, err := shared.Open(bobPriv, []string{bobPub}); err == nil {
+		t.Error("opened a config of an untrusted sender")
+	}
+}
+
+// A sender that lists itself as recipient in the file isn't trusted.
+func TestSharedConfigOpenForgedSender(t *testing.T) {
+	alicePub, _ := mustKey(t)
+	bobPub, bobPriv := mustKey(t)
+	malloryPub, malloryPriv := mustKey(t)
+
+	shared, err := SealShared(map[string]string{"github_token": "forged"}, malloryPriv,
+		map[string]string{"mallory": malloryPub, "bob": bobPub})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := shared.Open(bobPriv, []string{alicePub, bobPub}); err == nil {
+		t.Error("opened a config of a sender that is only listed in the file")
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	pub, priv := mustKey(t)
+
+	got, err := PublicKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != pub {
+		t.Errorf("PublicKey = %q, want %q", got, pub)
+	}
+}
+
+func mustKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	pub, priv, err := GenerateMemberKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pub, priv
+}
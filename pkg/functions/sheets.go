@@ -0,0 +1,169 @@
+package functions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetRange is the position of the values that were read from a sheet.
+type SheetRange struct {
+	// Tab is the name of the tab, empty for the first tab.
+	Tab string
+	// Row is the 1-based row and Column the 0-based column of the first
+	// value.
+	Row    int
+	Column int
+}
+
+// Cell returns the A1 notation of the cell at the 1-based row and 0-based
+// column relative to the start of the range.
+func (r SheetRange) Cell(row, column int) string {
+	prefix := ""
+	if r.Tab != "" {
+		prefix = "'" + strings.ReplaceAll(r.Tab, "'", "''") + "'!"
+	}
+
+	return fmt.Sprintf("%s%s%d", prefix, ColumnName(r.Column+column), r.Row+row-1)
+}
+
+// ParseRange parses a range in A1 notation like it is returned by the sheets
+// api, e.g. 'Speakers'!B3:Z100.
+func ParseRange(a1 string) (SheetRange, error) {
+	r := SheetRange{Row: 1}
+
+	cells := a1
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		r.Tab, cells = a1[:i], a1[i+1:]
+		if strings.HasPrefix(r.Tab, "'") && strings.HasSuffix(r.Tab, "'") && len(r.Tab) > 1 {
+			r.Tab = strings.ReplaceAll(r.Tab[1:len(r.Tab)-1], "''", "'")
+		}
+	}
+
+	start, _, _ := strings.Cut(cells, ":")
+	m := a1Cell.FindStringSubmatch(start)
+	if m == nil {
+		return r, errors.Errorf("invalid range %q", a1)
+	}
+
+	if m[1] != "" {
+		r.Column = columnIndex(m[1])
+	}
+	if m[2] != "" {
+		r.Row, _ = strconv.Atoi(m[2])
+	}
+
+	return r, nil
+}
+
+var a1Cell = regexp.MustCompile(`^([A-Za-z]*)([0-9]*)$`)
+
+// ReadSheet returns the values in the range (A1 notation or the name of a
+// tab) of the spreadsheet as records, like they are read from a csv export,
+// and the position of the values in the sheet.
+func ReadSheet(srv *sheets.Service, spreadsheetID, readRange string) ([][]string, SheetRange, error) {
+	res, err := srv.Spreadsheets.Values.Get(spreadsheetID, readRange).Do()
+	if err != nil {
+		return nil, SheetRange{}, errors.Wrapf(err, "read %s of sheet %s", readRange, spreadsheetID)
+	}
+
+	origin, err := ParseRange(res.Range)
+	if err != nil {
+		return nil, SheetRange{}, errors.Wrapf(err, "read %s of sheet %s", readRange, spreadsheetID)
+	}
+
+	records := make([][]string, 0, len(res.Values))
+	for _, row := range res.Values {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = fmt.Sprint(value)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, origin, nil
+}
+
+// RowStatus is the import result of a single row.
+type RowStatus struct {
+	// Row is the 1-based number of the row in the values that were read.
+	Row    int
+	Status string
+	Reason string
+	Image  string
+	Time   time.Time
+}
+
+// StatusColumns are the indexes of the columns the results are written to,
+// relative to the values that were read, -1 for columns that aren't written.
+type StatusColumns struct {
+	Status int
+	Reason int
+	Image  int
+	Time   int
+}
+
+// WriteStatus writes the results to the columns of the range the rows were
+// read from in a single request.
+func WriteStatus(srv *sheets.Service, spreadsheetID string, origin SheetRange, columns StatusColumns, statuses []RowStatus) error {
+	data := []*sheets.ValueRange{}
+	cell := func(col, row int, value string) {
+		if col < 0 {
+			return
+		}
+
+		data = append(data, &sheets.ValueRange{
+			Range:  origin.Cell(row, col),
+			Values: [][]interface{}{{value}},
+		})
+	}
+
+	for _, s := range statuses {
+		cell(columns.Status, s.Row, s.Status)
+		cell(columns.Reason, s.Row, s.Reason)
+		cell(columns.Image, s.Row, s.Image)
+		cell(columns.Time, s.Row, s.Time.Format(time.RFC3339))
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	_, err := srv.Spreadsheets.Values.BatchUpdate(spreadsheetID, &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "RAW",
+		Data:             data,
+	}).Do()
+	if err != nil {
+		return errors.Wrapf(err, "write status to sheet %s", spreadsheetID)
+	}
+
+	return nil
+}
+
+// ColumnName returns the letters of the column with the 0-based index, e.g.
+// 0 is A and 27 is AB.
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+// columnIndex returns the 0-based index of the column letters.
+func columnIndex(name string) int {
+	index := 0
+	for _, c := range strings.ToUpper(name) {
+		index = index*26 + int(c-'A') + 1
+	}
+
+	return index - 1
+}
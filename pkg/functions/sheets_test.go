@@ -0,0 +1,111 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want SheetRange
+	}{
+		{"Sheet1!A1:ZZ1000", SheetRange{Tab: "Sheet1", Row: 1, Column: 0}},
+		{"'Form responses 1'!B3:Z", SheetRange{Tab: "Form responses 1", Row: 3, Column: 1}},
+		{"'Speaker''s tab'!AB10:AC12", SheetRange{Tab: "Speaker's tab", Row: 10, Column: 27}},
+		{"Team!C:F", SheetRange{Tab: "Team", Row: 1, Column: 2}},
+		{"Team!5:9", SheetRange{Tab: "Team", Row: 5, Column: 0}},
+		{"B2:F10", SheetRange{Row: 2, Column: 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRange(tt.in)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRange(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRange("Team!not a range"); err == nil {
+		t.Error("ParseRange accepted an invalid range")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := ColumnName(index); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", index, got, want)
+		}
+		if got := columnIndex(want); got != index {
+			t.Errorf("columnIndex(%q) = %d, want %d", want, got, index)
+		}
+	}
+}
+
+// The status is written relative to the range the api returned, not to A1 of
+// the first tab.
+func TestWriteStatusRange(t *testing.T) {
+	var written []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(sheets.ValueRange{
+				Range: "'Speakers'!B3:E5",
+				Values: [][]interface{}{
+					{"Name", "Image", "Status"},
+					{"Jane Doe", "jane.png"},
+				},
+			})
+
+		case http.MethodPost:
+			req := sheets.BatchUpdateValuesRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			for _, d := range req.Data {
+				written = append(written, d.Range)
+			}
+			_, _ = w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	srv, err := sheets.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, origin, err := ReadSheet(srv, "sheet", "Speakers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := MapColumns(records, ColumnMapping{"name": {Header: "Name", Required: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := StatusColumns{Status: table.Column("Status"), Reason: -1, Image: -1, Time: -1}
+	statuses := []RowStatus{{Row: table.Rows[0].Number, Status: "published", Time: time.Now()}}
+
+	if err := WriteStatus(srv, "sheet", origin, columns, statuses); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"'Speakers'!D4"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written to %v, want %v", written, want)
+	}
+}
@@ -0,0 +1,98 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// DefaultRevokeURL is the google oauth revocation endpoint.
+const DefaultRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// Token is the representation of an oauth token in the config file. Next to
+// the oauth2 fields it keeps the scopes that were granted, which the token
+// response contains but oauth2.Token doesn't serialize. Tokens written before
+// the scope was stored simply have an empty Scope.
+type Token struct {
+	oauth2.Token
+	Scope string `json:"scope,omitempty"`
+}
+
+// NewToken wraps a token as returned from an exchange or a refresh.
+func NewToken(token *oauth2.Token) *Token {
+	t := &Token{Token: *token}
+	if scope, ok := token.Extra("scope").(string); ok {
+		t.Scope = scope
+	}
+
+	return t
+}
+
+// ParseToken decodes a token as stored in the config file.
+func ParseToken(data string) (*Token, error) {
+	t := &Token{}
+	if err := json.NewDecoder(bytes.NewBufferString(data)).Decode(t); err != nil {
+		return nil, errors.Wrap(err, "decode token")
+	}
+
+	return t, nil
+}
+
+// Encode returns the token in the format stored in the config file.
+func (t *Token) Encode() (string, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(t); err != nil {
+		return "", errors.Wrap(err, "encode token to json")
+	}
+
+	return buf.String(), nil
+}
+
+// Scopes returns the granted scopes, or nil if they are unknown.
+func (t *Token) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// RevokeToken revokes the token at the oauth revocation endpoint. The refresh
+// token is revoked if present, which invalidates the access tokens issued for
+// it as well. Tokens that are already invalid count as revoked.
+func RevokeToken(ctx context.Context, revokeURL string, token *Token) error {
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	if value == "" {
+		return errors.New("token contains neither a refresh nor an access token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(url.Values{"token": {value}}.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "create revoke request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "revoke token")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "invalid_token") {
+		return nil
+	}
+
+	return errors.Errorf("revoke token: %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,53 @@
+package functions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestRevokeToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{"revoked", http.StatusOK, `{}`, false},
+		{"already invalid", http.StatusBadRequest, `{"error":"invalid_token","error_description":"Token expired or revoked"}`, false},
+		{"bad request", http.StatusBadRequest, `{"error":"invalid_request"}`, true},
+		{"server error", http.StatusInternalServerError, `backend error`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var revoked string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				revoked = r.PostFormValue("token")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			token := &Token{Token: oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}}
+			err := RevokeToken(context.Background(), server.URL, token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RevokeToken() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			// the refresh token revokes the access tokens issued for it too
+			if revoked != "refresh" {
+				t.Errorf("revoked %q, want the refresh token", revoked)
+			}
+		})
+	}
+}
+
+func TestRevokeTokenEmpty(t *testing.T) {
+	if err := RevokeToken(context.Background(), "http://127.0.0.1:0", &Token{}); err == nil {
+		t.Error("revoked a token without a value")
+	}
+}
@@ -116,7 +116,10 @@ func UploadImage(client *s3.S3, bucket, filename, dir string, data []byte) (stri
 		return "", errors.Wrap(err, "upload to spaces")
 	}
 
-	url := "https://startupnights.fra1.digitaloceanspaces.com/" + filepath.Join(dir, filepath.Base(filename))
+	return ImageURL(filename, dir), nil
+}
 
-	return url, nil
+// ImageURL returns the public url of an image uploaded with UploadImage.
+func ImageURL(filename, dir string) string {
+	return "https://startupnights.fra1.digitaloceanspaces.com/" + filepath.Join(dir, filepath.Base(filename))
 }
@@ -0,0 +1,89 @@
+package functions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// VaultPrefix marks values that are encrypted by a vault.
+const VaultPrefix = "vault:"
+
+// Vault encrypts values with a key derived from a passphrase (scrypt) using
+// nacl/secretbox. Encrypted values are prefixed with VaultPrefix and can be
+// stored as plain strings.
+type Vault struct {
+	key [32]byte
+}
+
+// NewVaultSalt returns a random salt for the key derivation.
+func NewVaultSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(err, "generate salt")
+	}
+
+	return base64.StdEncoding.EncodeToString(salt), nil
+}
+
+// NewVault derives the key from the passphrase and the base64 encoded salt.
+func NewVault(passphrase, salt string) (*Vault, error) {
+	s, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode salt")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), s, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "derive key")
+	}
+
+	v := &Vault{}
+	copy(v.key[:], key)
+
+	return v, nil
+}
+
+// IsEncrypted reports whether the value was encrypted by a vault.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, VaultPrefix)
+}
+
+func (v *Vault) Encrypt(value string) (string, error) {
+	nonce := [24]byte{}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", errors.Wrap(err, "generate nonce")
+	}
+
+	out := secretbox.Seal(nonce[:], []byte(value), &nonce, &v.key)
+	return VaultPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+func (v *Vault) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, VaultPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "decode encrypted value")
+	}
+
+	if len(data) < 24 {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	nonce := [24]byte{}
+	copy(nonce[:], data[:24])
+
+	out, ok := secretbox.Open(nil, data[24:], &nonce, &v.key)
+	if !ok {
+		return "", errors.New("decrypt value: wrong passphrase or corrupted value")
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,47 @@
+package functions
+
+import (
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	salt, err := NewVaultSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewVault("passphrase", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := v.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Errorf("%q isn't marked as encrypted", encrypted)
+	}
+
+	got, err := v.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "secret" {
+		t.Errorf("Decrypt = %q, want %q", got, "secret")
+	}
+
+	wrong, err := NewVault("wrong", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Decrypt(encrypted); err == nil {
+		t.Error("decrypted with the wrong passphrase")
+	}
+
+	for _, value := range []string{"secret", VaultPrefix + "not base64", VaultPrefix + "c2hvcnQ="} {
+		if _, err := v.Decrypt(value); err == nil {
+			t.Errorf("Decrypt(%q) succeeded", value)
+		}
+	}
+}